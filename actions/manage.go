@@ -1,13 +1,13 @@
 package actions
 
 import (
-	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/util"
@@ -50,16 +50,7 @@ func ImportAction(do *definitions.Do) error {
 			return err
 		}
 
-		if logger.Level > 0 {
-			err = util.GetFromIPFS(s[1], fileName, logger.Writer)
-		} else {
-			err = util.GetFromIPFS(s[1], fileName, bytes.NewBuffer([]byte{}))
-		}
-
-		if err != nil {
-			return err
-		}
-		return nil
+		return ipfs.Node.Get(s[1], fileName)
 	}
 
 	if strings.Contains(s[0], "github") {
@@ -181,22 +172,10 @@ func RmAction(do *definitions.Do) error {
 }
 
 func exportFile(actionName string) (string, error) {
-	var err error
 	fileName := util.GetFileByNameAndType("actions", actionName)
 	if fileName == "" {
 		return "", fmt.Errorf("no file to export")
 	}
 
-	var hash string
-	if logger.Level > 0 {
-		hash, err = util.SendToIPFS(fileName, logger.Writer)
-	} else {
-		hash, err = util.SendToIPFS(fileName, bytes.NewBuffer([]byte{}))
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	return hash, nil
+	return ipfs.Node.Add(fileName)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/chains"
 	"github.com/eris-ltd/eris-cli/definitions"
@@ -53,11 +54,16 @@ func StartServicesAndChains(do *definitions.Do) error {
 
 	doChns := definitions.NowDo()
 	doChns.Name = do.Action.Chain
+	doChns.Height = 1
+	doChns.Timeout = uint(chains.DefaultWaitTimeout / time.Second)
 	if doChns.Name == "" {
 		logger.Debugf("No chain to start.\n")
 	} else {
 		logger.Debugf("Starting Chain. Name =>\t\t%v\n", doChns.Name)
-		if err := chains.StartChain(do); err != nil {
+		if err := chains.StartChain(doChns); err != nil {
+			return err
+		}
+		if err := chains.WaitChain(doChns); err != nil {
 			return err
 		}
 	}
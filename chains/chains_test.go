@@ -1,11 +1,18 @@
 package chains
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
 	def "github.com/eris-ltd/eris-cli/definitions"
@@ -15,6 +22,7 @@ import (
 	"github.com/eris-ltd/eris-cli/util"
 	"github.com/eris-ltd/eris-cli/version"
 
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 )
 
@@ -113,9 +121,51 @@ func TestChainGraduate(t *testing.T) {
 		t.Fail()
 	}
 
+	if srvDef.ChainName != chainName || srvDef.ChainType != loaders.DefaultChainType {
+		logger.Errorf("FAILURE: improper chain on GRADUATE. expected: %s:%s\tgot: %s:%s\n", chainName, loaders.DefaultChainType, srvDef.ChainName, srvDef.ChainType)
+		t.Fail()
+	}
+
 	//	testExistAndRun(t, chainName, true, true)
 }
 
+func TestChainFromServiceDef(t *testing.T) {
+	srv := def.BlankServiceDefinition()
+	srv.Name = "graduated"
+	srv.ServiceID = "graduated_id"
+	srv.ChainName = "dev"
+	srv.ChainType = "mint"
+	srv.ErisDBVersion = "0.11"
+	srv.Service.Command = loaders.ErisChainStart
+	srv.Service.Ports = []string{"46656:46656"}
+	srv.Service.Environment = []string{"CHAIN_ID=graduated_id", "CHAIN_TYPE=mint", "LOG=debug"}
+	srv.Maintainer.Name = "marmot"
+	srv.Location.Repository = "github.com/eris-ltd/dev"
+	srv.Operations.ContainerNumber = 1
+
+	chain := loaders.ChainFromServiceDef(srv)
+	if chain.Name != "dev" || chain.ChainID != "graduated_id" || chain.ChainType != "mint" || chain.ErisDBVersion != "0.11" {
+		logger.Errorf("FAILURE: improper chain on DEMOTE. expected: dev:graduated_id:mint:0.11\tgot: %s:%s:%s:%s\n", chain.Name, chain.ChainID, chain.ChainType, chain.ErisDBVersion)
+		t.Fail()
+	}
+	if chain.Service.Command != "" || len(chain.Service.Environment) != 1 || len(chain.Service.Ports) != 1 {
+		logger.Errorf("FAILURE: improper service on DEMOTE. got: %v\n", chain.Service)
+		t.Fail()
+	}
+	if chain.Maintainer.Name != "marmot" || chain.Location.Repository != "github.com/eris-ltd/dev" {
+		logger.Errorf("FAILURE: metadata lost on DEMOTE. got: %v:%v\n", chain.Maintainer, chain.Location)
+		t.Fail()
+	}
+	if chain.Operations.DataContainerName != util.DataContainersName("dev", 1) {
+		logger.Errorf("FAILURE: improper data container on DEMOTE. expected: %s\tgot: %s\n", util.DataContainersName("dev", 1), chain.Operations.DataContainerName)
+		t.Fail()
+	}
+	if srv.Service.Command != loaders.ErisChainStart {
+		logger.Errorf("FAILURE: DEMOTE changed the service definition.\n")
+		t.Fail()
+	}
+}
+
 func TestLoadChainDefinition(t *testing.T) {
 	var e error
 	logger.Infof("Load chain def (from tests) =>\t%s\n", chainName)
@@ -153,6 +203,7 @@ func TestStartChain(t *testing.T) {
 	}
 
 	testExistAndRun(t, chainName, true, true)
+	testSmokeChain(t, chainName)
 }
 
 func TestLogsChain(t *testing.T) {
@@ -233,6 +284,44 @@ func TestRenameChain(t *testing.T) {
 	testExistAndRun(t, chainName, true, true)
 }
 
+func TestCloneChain(t *testing.T) {
+	do := def.NowDo()
+	do.Name = chainName
+	do.NewName = "testclone"
+	do.ChainID = "testclone"
+	logger.Infof("Cloning chain (from tests) =>\t%s:%s\n", do.Name, do.NewName)
+	if e := CloneChain(do); e != nil {
+		logger.Errorln(e)
+		t.FailNow()
+	}
+
+	testExistAndRun(t, chainName, true, true)
+
+	if !util.IsDataContainer("testclone", 1) {
+		logger.Errorf("FAILURE: no data container for the clone.\n")
+		t.Fail()
+	}
+
+	chain, err := loaders.LoadChainDefinition("testclone", false, 1)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if chain.ChainID != "testclone" {
+		logger.Errorf("FAILURE: improper chain_id on CLONE. expected: %s\tgot: %s\n", "testclone", chain.ChainID)
+		t.Fail()
+	}
+
+	do = def.NowDo()
+	do.Name = "testclone"
+	do.RmD = true
+	do.File = true
+	if e := RmChain(do); e != nil {
+		logger.Errorln(e)
+		t.Fail()
+	}
+}
+
 func TestKillChain(t *testing.T) {
 	// log.SetLoggers(2, os.Stdout, os.Stderr)
 	testExistAndRun(t, chainName, true, true)
@@ -304,6 +393,556 @@ func TestRmChain(t *testing.T) {
 	testExistAndRun(t, chainName, false, false)
 }
 
+func TestChainTypes(t *testing.T) {
+	typesDir := loaders.ChainTypesPath()
+	ifExit(os.MkdirAll(typesDir, 0755))
+	defer os.RemoveAll(typesDir)
+
+	typeFile := "image = \"eris/devchain\"\nstart_cmd = \"devchain run\"\n"
+	ifExit(ioutil.WriteFile(path.Join(typesDir, "devchain.toml"), []byte(typeFile), 0644))
+
+	chainFile := path.Join(common.BlockchainsPath, "typed.toml")
+	ifExit(ioutil.WriteFile(chainFile, []byte("name = \"typed\"\nchain_id = \"typed\"\nchain_type = \"devchain\"\n"), 0644))
+	defer os.Remove(chainFile)
+
+	chain, err := loaders.LoadChainDefinition("typed", false, 1)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if chain.ChainType != "devchain" {
+		logger.Errorf("FAILURE: improper chain type on LOAD. expected: %s\tgot: %s\n", "devchain", chain.ChainType)
+		t.Fail()
+	}
+
+	if chain.Service.Image != "eris/devchain" {
+		logger.Errorf("FAILURE: improper image on LOAD. expected: %s\tgot: %s\n", "eris/devchain", chain.Service.Image)
+		t.Fail()
+	}
+
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if typ.StartCmd != "devchain run" {
+		logger.Errorf("FAILURE: improper start command on LOAD. expected: %s\tgot: %s\n", "devchain run", typ.StartCmd)
+		t.Fail()
+	}
+
+	if typ, err = loaders.LoadChainType(""); err != nil || typ.StartCmd != loaders.ErisChainStart {
+		logger.Errorf("FAILURE: improper default chain type. expected: %s\tgot: %v:%v\n", loaders.ErisChainStart, typ, err)
+		t.Fail()
+	}
+
+	if _, err := loaders.LoadChainType("not_a_type"); err == nil {
+		logger.Errorf("FAILURE: expected an error loading an unknown chain type.\n")
+		t.Fail()
+	}
+}
+
+func TestPinChainVersion(t *testing.T) {
+	for image, pinned := range map[string]string{
+		"eris/erisdb:0.10":                "eris/erisdb:0.11",
+		"eris/erisdb":                     "eris/erisdb:0.11",
+		"localhost:5000/eris/erisdb:0.10": "localhost:5000/eris/erisdb:0.11",
+		"eris/erisdb@sha256:abc":          "eris/erisdb:0.11",
+	} {
+		if got := loaders.PinImage(image, "0.11"); got != pinned {
+			logger.Errorf("FAILURE: improper pinned image. expected: %s\tgot: %s\n", pinned, got)
+			t.Fail()
+		}
+	}
+
+	if got := loaders.PinImage("eris/erisdb:0.10", "sha256:abc"); got != "eris/erisdb@sha256:abc" {
+		logger.Errorf("FAILURE: improper pinned digest. expected: %s\tgot: %s\n", "eris/erisdb@sha256:abc", got)
+		t.Fail()
+	}
+
+	chainFile := path.Join(common.BlockchainsPath, "pinned.toml")
+	ifExit(ioutil.WriteFile(chainFile, []byte("name = \"pinned\"\nchain_id = \"pinned\"\n\n[service]\nimage = \"eris/erisdb:0.10\"\n"), 0644))
+	defer os.Remove(chainFile)
+
+	for _, version := range []string{"0.11", "0.12"} {
+		ifExit(setTOMLKey(chainFile, "erisdb_version", version))
+
+		chain, err := loaders.LoadChainDefinition("pinned", false, 1)
+		if err != nil {
+			logger.Errorln(err)
+			t.FailNow()
+		}
+
+		if chain.ErisDBVersion != version || chain.Service.Image != "eris/erisdb:"+version {
+			logger.Errorf("FAILURE: improper pinned chain. expected: %s\tgot: %s:%s\n", version, chain.ErisDBVersion, chain.Service.Image)
+			t.Fail()
+		}
+	}
+
+	raw, err := ioutil.ReadFile(chainFile)
+	ifExit(err)
+	if strings.Count(string(raw), "erisdb_version") != 1 || !strings.Contains(string(raw), "[service]\nimage") {
+		logger.Errorf("FAILURE: improper chain definition after SET.\n%s\n", raw)
+		t.Fail()
+	}
+}
+
+func TestThrowAwayChainTTL(t *testing.T) {
+	created := time.Now().Add(-2 * time.Hour)
+
+	chain := loaders.MockChainDefinition("ephemeral_0123abcd", "ephemeral_0123abcd", false, 1)
+	chain.Ephemeral = true
+	chain.Created = created.UTC().Format(time.RFC3339)
+	chain.TTL = "1h"
+
+	chainFile := path.Join(common.BlockchainsPath, chain.Name+".toml")
+	ifExit(WriteChainDefinitionFile(chain, chainFile))
+	defer os.Remove(chainFile)
+
+	chain, err := loaders.LoadChainDefinition(chain.Name, false, 1)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if !chain.Ephemeral || chain.TTL != "1h" {
+		logger.Errorf("FAILURE: improper ephemeral chain on LOAD. expected: true:1h\tgot: %t:%s\n", chain.Ephemeral, chain.TTL)
+		t.Fail()
+	}
+
+	for ttl, expired := range map[string]bool{"1h": true, "3h": false, "": false} {
+		chain.TTL = ttl
+		got, err := isExpired(chain, time.Now())
+		if err != nil {
+			logger.Errorln(err)
+			t.Fail()
+		}
+		if got != expired {
+			logger.Errorf("FAILURE: improper expiry for ttl %q. expected: %t\tgot: %t\n", ttl, expired, got)
+			t.Fail()
+		}
+	}
+
+	chain.TTL = "soon"
+	if _, err := isExpired(chain, time.Now()); err == nil {
+		logger.Errorf("FAILURE: expected an error for a bad ttl.\n")
+		t.Fail()
+	}
+
+	if !throwAwayName.MatchString(chain.Name) || throwAwayName.MatchString(chainName) {
+		logger.Errorf("FAILURE: improper throwaway name detection.\n")
+		t.Fail()
+	}
+}
+
+func TestChainPoolLease(t *testing.T) {
+	ifExit(os.MkdirAll(ChainPoolPath(), 0755))
+	defer os.RemoveAll(ChainPoolPath())
+
+	idle, gone := "pool_0123abcd", "pool_deadbeef"
+	for _, name := range []string{idle, gone, chainName} {
+		ifExit(ioutil.WriteFile(path.Join(ChainPoolPath(), name), []byte{}, 0644))
+	}
+
+	chainFile := path.Join(common.BlockchainsPath, idle+".toml")
+	ifExit(ioutil.WriteFile(chainFile, []byte("name = \""+idle+"\"\nchain_id = \""+idle+"\"\n"), 0644))
+	defer os.Remove(chainFile)
+
+	if pool := idlePoolChains(); len(pool) != 1 || pool[0] != idle {
+		logger.Errorf("FAILURE: improper idle pool chains. expected: [%s]\tgot: %v\n", idle, pool)
+		t.Fail()
+	}
+
+	if !leasePoolChain(idle) {
+		logger.Errorf("FAILURE: could not lease an idle pool chain.\n")
+		t.Fail()
+	}
+
+	if leasePoolChain(idle) {
+		logger.Errorf("FAILURE: leased the same pool chain twice.\n")
+		t.Fail()
+	}
+
+	if _, leased := leasedSince(idle); !leased {
+		logger.Errorf("FAILURE: lease of %s not recorded.\n", idle)
+		t.Fail()
+	}
+
+	if len(idlePoolChains()) != 0 {
+		logger.Errorf("FAILURE: leased chain still idle in the pool.\n")
+		t.Fail()
+	}
+}
+
+func TestSetGenesisChainID(t *testing.T) {
+	fileName := path.Join(common.DataContainersPath, "genesis_clone.json")
+	ifExit(ioutil.WriteFile(fileName, []byte(`{"chain_id":"old","accounts":[{"amount":18446744073709551615}]}`), 0644))
+	defer os.Remove(fileName)
+
+	ifExit(setGenesisChainID(fileName, "new"))
+
+	chainID, err := getChainIDFromGenesis(fileName, "")
+	if err != nil || chainID != "new" {
+		logger.Errorf("FAILURE: improper chain_id in genesis. expected: new\tgot: %s:%v\n", chainID, err)
+		t.Fail()
+	}
+	if raw, _ := ioutil.ReadFile(fileName); !strings.Contains(string(raw), "18446744073709551615") {
+		logger.Errorf("FAILURE: genesis amounts changed.\n%s\n", raw)
+		t.Fail()
+	}
+}
+
+func TestGCOnlyThrowAway(t *testing.T) {
+	ifExit(os.MkdirAll(ThrowAwayPath(), 0755))
+	defer os.RemoveAll(ThrowAwayPath())
+
+	// a folder which only looks like a throwaway chain's
+	lookalike := path.Join(common.DataContainersPath, "foo_deadbeef")
+	ifExit(os.MkdirAll(lookalike, 0755))
+	defer os.RemoveAll(lookalike)
+
+	orphan := "orphan_0123abcd"
+	ifExit(ioutil.WriteFile(path.Join(ThrowAwayPath(), orphan), []byte{}, 0644))
+
+	garbage := findGarbageChains(time.Now())
+	if _, ok := garbage["foo_deadbeef"]; ok {
+		logger.Errorf("FAILURE: collected a folder by its name alone.\n")
+		t.Fail()
+	}
+	if garbage[orphan] != "no definition" {
+		logger.Errorf("FAILURE: improper reason for a recorded throwaway chain. expected: no definition\tgot: %s\n", garbage[orphan])
+		t.Fail()
+	}
+
+	if err := RemoveThrowAwayChain("foo_deadbeef", def.BlankOperation()); err == nil {
+		logger.Errorf("FAILURE: removed a chain which is not throwaway.\n")
+		t.Fail()
+	}
+	if _, err := os.Stat(lookalike); err != nil {
+		logger.Errorf("FAILURE: the folder of a chain which is not throwaway was removed.\n")
+		t.Fail()
+	}
+}
+
+func TestStatusChainRPC(t *testing.T) {
+	node := newStubNode(7)
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	status, err := statusFromRPC(addr)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if status.BlockHeight != 7 {
+		logger.Errorf("FAILURE: improper block height on STATUS. expected: %d\tgot: %d\n", 7, status.BlockHeight)
+		t.Fail()
+	}
+
+	if status.ChainID != chainName {
+		logger.Errorf("FAILURE: improper chain_id on STATUS. expected: %s\tgot: %s\n", chainName, status.ChainID)
+		t.Fail()
+	}
+
+	if len(status.Validators) != 1 || len(status.Peers) != 2 {
+		logger.Errorf("FAILURE: improper validators or peers on STATUS. expected: 1:2\tgot: %d:%d\n", len(status.Validators), len(status.Peers))
+		t.Fail()
+	}
+
+	if status.Sync != "synced" {
+		logger.Errorf("FAILURE: improper sync state on STATUS. expected: synced\tgot: %s\n", status.Sync)
+		t.Fail()
+	}
+
+	node.setBlock(7, time.Now().Add(-time.Hour))
+	if status, err = statusFromRPC(addr); err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if status.Sync != "catching up" {
+		logger.Errorf("FAILURE: improper sync state on STATUS. expected: catching up\tgot: %s\n", status.Sync)
+		t.Fail()
+	}
+}
+
+func TestWaitChainRPC(t *testing.T) {
+	node := newStubNode(0)
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	interval := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	defer func() { waitPollInterval = interval }()
+
+	addr := func() (string, error) {
+		return strings.TrimPrefix(server.URL, "http://"), nil
+	}
+
+	if err := waitForRPC(addr, 3, 100*time.Millisecond); err == nil {
+		logger.Errorf("FAILURE: expected WAIT to time out on a chain stuck at block 0.\n")
+		t.Fail()
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		node.setBlock(3, time.Now())
+	}()
+
+	if err := waitForRPC(addr, 3, 5*time.Second); err != nil {
+		logger.Errorln(err)
+		t.Fail()
+	}
+}
+
+func TestJoinNodeConfig(t *testing.T) {
+	if err := checkSeeds([]string{"10.0.0.1:46656", "seed.example.com:46656"}); err != nil {
+		logger.Errorln(err)
+		t.Fail()
+	}
+	for _, seeds := range [][]string{{}, {"10.0.0.1"}} {
+		if err := checkSeeds(seeds); err == nil {
+			logger.Errorf("FAILURE: expected an error on bad seeds: %v\n", seeds)
+			t.Fail()
+		}
+	}
+
+	moniker, seeds := `my "node"`, "10.0.0.1:46656,10.0.0.2:46656"
+	fileName := path.Join(os.TempDir(), "eris_join_config.toml")
+	defer os.Remove(fileName)
+	ifExit(writeNodeConfig(fileName, moniker, seeds))
+
+	// only the moniker and seeds differ from the default config
+	read, expected := map[string]interface{}{}, map[string]interface{}{}
+	if _, err := toml.DecodeFile(fileName, &read); err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	ifExit(toml.Unmarshal([]byte(ini.DefChainConfig()), &expected))
+	expected["moniker"], expected["seeds"] = moniker, seeds
+	if !reflect.DeepEqual(read, expected) {
+		logger.Errorf("FAILURE: improper node config on JOIN. expected: %v\tgot: %v\n", expected, read)
+		t.Fail()
+	}
+
+	node := newStubNode(3)
+	node.setBlock(3, time.Now().Add(-time.Hour))
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	interval := statusWatchInterval
+	statusWatchInterval = 10 * time.Millisecond
+	defer func() { statusWatchInterval = interval }()
+
+	addr := func() (string, error) {
+		return strings.TrimPrefix(server.URL, "http://"), nil
+	}
+	if err := followSync(addr, 50*time.Millisecond); err == nil {
+		logger.Errorf("FAILURE: expected JOIN to time out on a node which is catching up.\n")
+		t.Fail()
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		node.setBlock(9, time.Now())
+	}()
+	if err := followSync(addr, 5*time.Second); err != nil {
+		logger.Errorln(err)
+		t.Fail()
+	}
+}
+
+func TestSmokeSpec(t *testing.T) {
+	node := newStubNode(3)
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	interval := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	defer func() { waitPollInterval = interval }()
+
+	specFile := path.Join(os.TempDir(), "eris_smoke.toml")
+	defer os.Remove(specFile)
+	if err := ioutil.WriteFile(specFile, []byte(`
+[[check]]
+name = "block height increases"
+method = "status"
+field = "latest_block_height"
+op = "increases"
+within = "2s"
+
+[[check]]
+name = "account balance"
+method = "get_account"
+params = ["37236DF251AB70022B1DA351F08A20FB52443E37"]
+field = "account.balance"
+op = "eq"
+value = 1000
+
+[[check]]
+name = "four validators"
+method = "list_validators"
+field = "bonded_validators.#"
+op = "eq"
+value = 4
+
+[[check]]
+method = "no_such_method"
+op = "exists"
+`), 0644); err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	spec, err := LoadSmokeSpec(specFile)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		node.setBlock(4, time.Now())
+	}()
+	started := time.Now()
+	results := runSmokeSpec(strings.TrimPrefix(server.URL, "http://"), spec)
+
+	// pass, pass, fail (there is one validator), error
+	expected := []string{"pass", "pass", "fail", "error"}
+	for i, r := range results {
+		got := "pass"
+		if r.Error != "" {
+			got = "error"
+		} else if r.Failure != "" {
+			got = "fail"
+		}
+		if got != expected[i] {
+			logger.Errorf("FAILURE: improper result on TEST of %s. expected: %s\tgot: %s (%s%s)\n", r.Check.Name, expected[i], got, r.Failure, r.Error)
+			t.Fail()
+		}
+	}
+
+	report := junitReport(chainName, spec, results, started)
+	if s := report.Suites[0]; s.Tests != 4 || s.Failures != 1 || s.Errors != 1 {
+		logger.Errorf("FAILURE: improper JUnit counts. expected: 4:1:1\tgot: %d:%d:%d\n", s.Tests, s.Failures, s.Errors)
+		t.Fail()
+	}
+}
+
+func TestSmokeCheckNoRPC(t *testing.T) {
+	// eth has no RPC server eris knows how to check
+	chainFile := path.Join(common.BlockchainsPath, "norpc.toml")
+	ifExit(ioutil.WriteFile(chainFile, []byte("name = \"norpc\"\nchain_id = \"norpc\"\nchain_type = \"eth\"\n"), 0644))
+	defer os.Remove(chainFile)
+
+	if err := checkChain("norpc", 1); err != nil {
+		logger.Errorf("FAILURE: expected a chain without RPC to pass its smoke check, got: %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestNodeConfigChecks(t *testing.T) {
+	for key, value := range map[string]string{"moniker": "node", "seeds": "10.0.0.1:46656", "log_level": "info", "db_backend": "memdb", "rpc_laddr": "0.0.0.0:46657"} {
+		if err := checkNodeConfigKey(key, value); err != nil {
+			logger.Errorln(err)
+			t.Fail()
+		}
+	}
+	for key, value := range map[string]string{"moniker": "", "seeds": "10.0.0.1", "log_level": "loud", "db_backend": "mysql", "rpc_laddr": "46657", "fast_sync": "true"} {
+		if err := checkNodeConfigKey(key, value); err == nil {
+			logger.Errorf("FAILURE: expected an error on CONFIG SET of %s = %s\n", key, value)
+			t.Fail()
+		}
+	}
+
+	if err := checkNodeConfig([]byte("moniker = \"node\"\nfast_sync = true\nlog_level = \"info\"\n")); err != nil {
+		logger.Errorln(err)
+		t.Fail()
+	}
+	for _, raw := range []string{"moniker = \"node", "log_level = \"loud\"\n", "db_backend = 1\n"} {
+		if err := checkNodeConfig([]byte(raw)); err == nil {
+			logger.Errorf("FAILURE: expected an error on CONFIG EDIT of %q\n", raw)
+			t.Fail()
+		}
+	}
+}
+
+// stubNode stands in for the RPC server of a running chain.
+type stubNode struct {
+	sync.Mutex
+	height    int
+	blockTime time.Time
+}
+
+func newStubNode(height int) *stubNode {
+	return &stubNode{height: height, blockTime: time.Now()}
+}
+
+func (n *stubNode) setBlock(height int, blockTime time.Time) {
+	n.Lock()
+	defer n.Unlock()
+	n.height, n.blockTime = height, blockTime
+}
+
+func (n *stubNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.Lock()
+	defer n.Unlock()
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "status":
+		result = map[string]interface{}{
+			"node_info":           map[string]string{"moniker": "stub", "network": chainName},
+			"latest_block_hash":   "AB12",
+			"latest_block_height": n.height,
+			"latest_block_time":   n.blockTime.UnixNano(),
+		}
+	case "list_validators":
+		result = map[string]interface{}{
+			"bonded_validators": []map[string]interface{}{{"address": "37236DF251AB70022B1DA351F08A20FB52443E37", "voting_power": 10}},
+		}
+	case "get_account":
+		result = map[string]interface{}{
+			"account": map[string]interface{}{"address": req.Params[0], "balance": 1000},
+		}
+	case "net_info":
+		result = map[string]interface{}{
+			"peers": []map[string]interface{}{
+				{"node_info": map[string]string{"moniker": "one", "host": "10.0.0.1"}, "is_outbound": true},
+				{"node_info": map[string]string{"moniker": "two", "host": "10.0.0.2"}},
+			},
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]string{"jsonrpc": "2.0", "id": req.ID, "error": "unknown method " + req.Method})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": []interface{}{1, result}})
+}
+
+// testSmokeChain runs the default checks against a running chain. On
+// CI the results are kept with the other test reports.
+func testSmokeChain(t *testing.T, chainName string) {
+	do := def.NowDo()
+	do.Name = chainName
+	do.Operations.ContainerNumber = 1
+	if reports := os.Getenv("CIRCLE_TEST_REPORTS"); reports != "" {
+		do.Path = path.Join(reports, "chains", chainName+".xml")
+	}
+	if err := SmokeTestChain(do); err != nil {
+		logger.Errorln(err)
+		t.Fail()
+	}
+}
+
 func testExistAndRun(t *testing.T, chainName string, toExist, toRun bool) {
 	var exist, run bool
 	logger.Infof("\nTesting whether (%s) is running? (%t) and existing? (%t)\n", chainName, toRun, toExist)
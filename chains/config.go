@@ -0,0 +1,276 @@
+package chains
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// where a chain's data container keeps the chain's directories
+const chainContainerRoot = "/home/eris/.eris"
+
+// the node config keys eris knows how to check
+var nodeConfigKeys = map[string]func(string) error{
+	"moniker": func(v string) error {
+		if v == "" {
+			return fmt.Errorf("moniker cannot be empty")
+		}
+		return nil
+	},
+	"seeds": func(v string) error {
+		if v == "" {
+			return nil
+		}
+		return checkSeeds(strings.Split(v, ","))
+	},
+	"log_level":  oneOf("debug", "info", "notice", "warn", "error"),
+	"db_backend": oneOf("leveldb", "memdb"),
+	"rpc_laddr": func(v string) error {
+		if _, _, err := net.SplitHostPort(v); err != nil {
+			return fmt.Errorf("rpc_laddr should be host:port, not (%s)", v)
+		}
+		return nil
+	},
+}
+
+// ChainConfig reads and writes the config.toml of a chain's node inside
+// its data container. do.Args is one of
+//
+//	get [key]
+//	set key value
+//	edit
+//
+// After a change to a running chain the user is offered a restart;
+// do.Force restarts without asking.
+func ChainConfig(do *definitions.Do) error {
+	if len(do.Args) == 0 {
+		return fmt.Errorf("Please tell the marmots to get, set or edit the config.")
+	}
+
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return err
+	}
+	if typ.ConfigFile == "" {
+		return fmt.Errorf("Chains of type (%s) have no node config.", typ.Name)
+	}
+
+	dir, err := ioutil.TempDir("", "eris_config_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	containerFile := path.Join(chainContainerRoot, typ.ChainsDir, do.Name, typ.ConfigFile)
+	// the data container, or the chain's named volumes
+	dataContainer, release, err := data.HoldData(chain.Name, chain.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if dataContainer == "" {
+		return fmt.Errorf("The marmots cannot find the data of %s.", chain.Name)
+	}
+	header, raw, err := copyFileOut(dataContainer, containerFile)
+	if err != nil {
+		return err
+	}
+	fileName := path.Join(dir, typ.ConfigFile)
+	if err := ioutil.WriteFile(fileName, raw, 0644); err != nil {
+		return err
+	}
+
+	switch do.Args[0] {
+	case "get":
+		return getNodeConfig(raw, do.Args[1:])
+	case "set":
+		if len(do.Args) != 3 {
+			return fmt.Errorf("Please give the marmots a key and a value to set.")
+		}
+		if err := checkNodeConfigKey(do.Args[1], do.Args[2]); err != nil {
+			return err
+		}
+		if err := setTOMLKey(fileName, do.Args[1], do.Args[2]); err != nil {
+			return err
+		}
+	case "edit":
+		if err := Editor(fileName); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("The marmots can get, set or edit the config, not (%s).", do.Args[0])
+	}
+
+	edited, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, raw) {
+		logger.Infoln("Config unchanged.")
+		return nil
+	}
+	if err := checkNodeConfig(edited); err != nil {
+		return err
+	}
+
+	if err := copyFileIn(dataContainer, path.Dir(containerFile), header, edited); err != nil {
+		return err
+	}
+	logger.Printf("Config updated =>\t\t%s\n", do.Name)
+
+	return offerRestart(chain, do.Force)
+}
+
+func getNodeConfig(raw []byte, keys []string) error {
+	if len(keys) == 0 {
+		logger.Printf("%s", raw)
+		return nil
+	}
+
+	config := map[string]interface{}{}
+	if _, err := toml.Decode(string(raw), &config); err != nil {
+		return fmt.Errorf("The marmots could not read the node config: %v", err)
+	}
+	value, ok := config[keys[0]]
+	if !ok {
+		return fmt.Errorf("The node config has no key (%s).", keys[0])
+	}
+	logger.Println(value)
+	return nil
+}
+
+func checkNodeConfigKey(key, value string) error {
+	check, ok := nodeConfigKeys[key]
+	if !ok {
+		known := []string{}
+		for k := range nodeConfigKeys {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		return fmt.Errorf("The marmots can set %s. Use [edit] for (%s).", strings.Join(known, ", "), key)
+	}
+	if err := check(value); err != nil {
+		return fmt.Errorf("Bad value for %s: %v", key, err)
+	}
+	return nil
+}
+
+// checkNodeConfig makes sure an edited config still parses and that
+// the keys eris knows about hold sensible values.
+func checkNodeConfig(raw []byte) error {
+	config := map[string]interface{}{}
+	if _, err := toml.Decode(string(raw), &config); err != nil {
+		return fmt.Errorf("The marmots could not read the edited node config. Not saving it.\n%v", err)
+	}
+	for key, value := range config {
+		if _, ok := nodeConfigKeys[key]; !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Bad value for %s: expected a string, got (%v). Not saving the config.", key, value)
+		}
+		if err := checkNodeConfigKey(key, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, value := range values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s, not (%s)", strings.Join(values, ", "), v)
+	}
+}
+
+// copyFileOut returns the tar header and contents of a single file
+// in a container.
+func copyFileOut(container, fileName string) (*tar.Header, []byte, error) {
+	var buf bytes.Buffer
+	opts := docker.CopyFromContainerOptions{
+		OutputStream: &buf,
+		Container:    container,
+		Resource:     fileName,
+	}
+	if err := util.DockerClient.CopyFromContainer(opts); err != nil {
+		return nil, nil, fmt.Errorf("The marmots could not copy %s out of %s: %v", fileName, container, err)
+	}
+
+	reader := tar.NewReader(&buf)
+	header, err := reader.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := ioutil.ReadAll(reader)
+	return header, raw, err
+}
+
+// copyFileIn writes a file into dir inside a container, keeping the
+// name, mode and owner from header.
+func copyFileIn(container, dir string, header *tar.Header, raw []byte) error {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	header.Size = int64(len(raw))
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return perform.DockerImportTar(container, dir, &buf)
+}
+
+func offerRestart(chain *definitions.Chain, force bool) error {
+	if !IsChainRunning(chain) {
+		return nil
+	}
+
+	if !force {
+		var input string
+		fmt.Print("The chain has to be restarted for the change to take effect. Restart it now? (y/N): ")
+		fmt.Scanln(&input)
+		if input != "y" && input != "Y" && input != "yes" && input != "Yes" {
+			logger.Printf("Restart later with =>\t\t[eris chains stop %s && eris chains start %s]\n", chain.Name, chain.Name)
+			return nil
+		}
+	}
+
+	logger.Printf("Restarting =>\t\t\t%s\n", chain.Name)
+	if err := perform.DockerStop(chain.Service, chain.Operations, 10); err != nil {
+		return err
+	}
+	start := definitions.NowDo()
+	start.Name = chain.Name
+	start.Operations.ContainerNumber = chain.Operations.ContainerNumber
+	return StartChain(start)
+}
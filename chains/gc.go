@@ -0,0 +1,197 @@
+package chains
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// DefaultThrowAwayTTL is how long a throwaway chain lives when
+// neither its definition nor eris chains new --ttl says otherwise.
+var DefaultThrowAwayTTL = 24 * time.Hour
+
+// names ThrowAwayChain gives its chains: <name>_<uuid prefix>
+var throwAwayName = regexp.MustCompile(`_[0-9a-f]{8}$`)
+
+// ThrowAwayPath holds an empty file for every ephemeral chain, written
+// before any of its containers. gc only collects chains recorded here
+// or marked ephemeral in their definition, never by name alone.
+func ThrowAwayPath() string {
+	return path.Join(BlockchainsPath, "throwaway")
+}
+
+// GCChains removes throwaway chains whose ttl has run out and the
+// remains of throwaway chains which lost either their definition
+// file or all of their containers. With do.DryRun it only reports;
+// without do.Force it asks before removing anything.
+func GCChains(do *definitions.Do) error {
+	garbage := findGarbageChains(time.Now())
+	if len(garbage) == 0 {
+		logger.Infoln("No throwaway chains to collect.")
+		return nil
+	}
+
+	names := []string{}
+	for name := range garbage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if do.DryRun || !do.Force {
+		for _, name := range names {
+			logger.Printf("Would remove =>\t\t\t%s (%s)\n", name, garbage[name])
+		}
+	}
+	if do.DryRun {
+		return nil
+	}
+	if !do.Force {
+		var input string
+		fmt.Printf("Remove these throwaway chains? (y/N): ")
+		fmt.Scanln(&input)
+		if input != "y" && input != "Y" && input != "yes" && input != "Yes" {
+			logger.Println("Nothing removed.")
+			return nil
+		}
+	}
+
+	for _, name := range names {
+		logger.Printf("Removing =>\t\t\t%s (%s)\n", name, garbage[name])
+		if err := RemoveThrowAwayChain(name, definitions.BlankOperation()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveThrowAwayChain stops and removes a throwaway chain's containers
+// and deletes its definition file and its data directory on the host.
+// It works whether or not the definition file is still around, but
+// only for chains recorded as throwaway. Data a service definition of
+// the same name could own is left alone.
+func RemoveThrowAwayChain(name string, ops *definitions.Operation) error {
+	if !IsThrowAwayChain(name) {
+		return fmt.Errorf("The marmots only remove throwaway chains and %s is not one.", name)
+	}
+	cNum := ops.ContainerNumber
+	if cNum == 0 {
+		cNum = 1
+	}
+	chain := loaders.MockChainDefinition(name, name, false, cNum)
+	ownedByService := util.GetFileByNameAndType("services", name) != ""
+
+	if IsChainRunning(chain) {
+		if err := perform.DockerStop(chain.Service, chain.Operations, 0); err != nil {
+			return err
+		}
+	}
+	if err := perform.DockerRemove(chain.Service, chain.Operations, !ownedByService); err != nil {
+		return err
+	}
+
+	if ownedByService {
+		logger.Printf("Keeping the data of the %s service.\n", name)
+	} else {
+		logger.Debugf("Removing latent dir =>\t\t%s\n", path.Join(DataContainersPath, name))
+		if err := os.RemoveAll(path.Join(DataContainersPath, name)); err != nil {
+			return err
+		}
+	}
+	logger.Debugf("Removing definition =>\t\t%s\n", path.Join(BlockchainsPath, name+".toml"))
+	if err := os.Remove(path.Join(BlockchainsPath, name+".toml")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	os.Remove(path.Join(ChainPoolPath(), name))
+	os.Remove(path.Join(chainLeasePath(), name))
+	os.Remove(path.Join(ThrowAwayPath(), name))
+	return nil
+}
+
+// IsThrowAwayChain tells whether name was made as a throwaway chain:
+// it is recorded under ThrowAwayPath() or its definition is ephemeral.
+func IsThrowAwayChain(name string) bool {
+	if _, err := os.Stat(path.Join(ThrowAwayPath(), name)); err == nil {
+		return true
+	}
+	if util.GetFileByNameAndType("chains", name) == "" {
+		return false
+	}
+	chain, err := loaders.LoadChainDefinition(name, false, 1)
+	return err == nil && chain.Ephemeral
+}
+
+// findGarbageChains maps the names of collectable chains to
+// the reason they are collected.
+func findGarbageChains(now time.Time) map[string]string {
+	garbage := map[string]string{}
+
+	known := map[string]bool{}
+	for _, name := range util.GetGlobalLevelConfigFilesByType("chains", false) {
+		known[name] = true
+
+		chain, err := loaders.LoadChainDefinition(name, false, 1)
+		if err != nil || !chain.Ephemeral {
+			continue
+		}
+
+		// a leased pool chain's ttl runs from its lease, so one which
+		// sat idle for a while is not pulled out from under a test
+		if leased, ok := leasedSince(name); ok {
+			chain.Created = leased.UTC().Format(time.RFC3339)
+		}
+		if expired, err := isExpired(chain, now); err != nil {
+			logger.Infof("Skipping chain =>\t\t%s: %v\n", name, err)
+		} else if expired {
+			garbage[name] = "expired"
+		} else if !IsChainExisting(chain) && !util.IsDataContainer(name, 1) {
+			garbage[name] = "no containers"
+		}
+	}
+
+	// remains of throwaway chains whose definition file was removed
+	records, _ := ioutil.ReadDir(ThrowAwayPath())
+	for _, r := range records {
+		if !known[r.Name()] {
+			garbage[r.Name()] = "no definition"
+		}
+	}
+
+	return garbage
+}
+
+// isExpired checks an ephemeral chain's ttl against its creation time.
+func isExpired(chain *definitions.Chain, now time.Time) (bool, error) {
+	created, err := time.Parse(time.RFC3339, chain.Created)
+	if err != nil {
+		return false, fmt.Errorf("bad created time (%s): %v", chain.Created, err)
+	}
+
+	ttl, err := ttlOrDefault(chain.TTL)
+	if err != nil {
+		return false, err
+	}
+
+	d, _ := time.ParseDuration(ttl)
+	return now.Sub(created) > d, nil
+}
+
+func ttlOrDefault(ttl string) (string, error) {
+	if ttl == "" {
+		return DefaultThrowAwayTTL.String(), nil
+	}
+	if _, err := time.ParseDuration(ttl); err != nil {
+		return "", fmt.Errorf("bad ttl (%s): %v", ttl, err)
+	}
+	return ttl, nil
+}
@@ -0,0 +1,158 @@
+package chains
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/initialize"
+	"github.com/eris-ltd/eris-cli/ipfs"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+)
+
+// JoinChain sets up a node for the network described by the genesis
+// file (or IPFS hash) do.GenesisFile, peering with the do.Seeds nodes.
+// The node is started and its sync progress is reported until it has
+// caught up or do.Timeout seconds have passed (0 waits for ever).
+func JoinChain(do *definitions.Do) error {
+	if do.Name == "" {
+		return fmt.Errorf("Please give the marmots a name for the chain.")
+	}
+	if do.GenesisFile == "" {
+		return fmt.Errorf("Please give the marmots the network's genesis file or IPFS hash with --genesis.")
+	}
+	if err := checkSeeds(do.Seeds); err != nil {
+		return err
+	}
+	if isKnownChain(do.Name) {
+		return fmt.Errorf("The marmots already know a chain called %s.", do.Name)
+	}
+
+	dir, err := ioutil.TempDir("", "eris_join_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	genesis, err := fetchGenesis(do.GenesisFile, dir)
+	if err != nil {
+		return err
+	}
+
+	moniker := do.Moniker
+	if moniker == "" {
+		moniker, _ = os.Hostname()
+	}
+	seeds := strings.Join(do.Seeds, ",")
+
+	configFile := path.Join(dir, "config.toml")
+	if err := writeNodeConfig(configFile, moniker, seeds); err != nil {
+		return err
+	}
+
+	setup := definitions.NowDo()
+	setup.Name = do.Name
+	setup.ChainType = do.ChainType
+	setup.GenesisFile = genesis
+	setup.ConfigFile = configFile
+	setup.Operations.ContainerNumber = do.Operations.ContainerNumber
+	setup.Operations.PublishAllPorts = do.Operations.PublishAllPorts
+	if err := NewChain(setup); err != nil {
+		return err
+	}
+	logger.Printf("Joining network =>\t\t%s:%s\n", setup.ChainID, seeds)
+
+	start := definitions.NowDo()
+	start.Name = do.Name
+	start.Operations.ContainerNumber = do.Operations.ContainerNumber
+	start.Operations.PublishAllPorts = do.Operations.PublishAllPorts
+	if err := StartChain(start); err != nil {
+		return err
+	}
+
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	return followSync(func() (string, error) {
+		return ChainRPCAddress(chain)
+	}, time.Duration(do.Timeout)*time.Second)
+}
+
+// followSync prints the block height and peer count of a node every
+// time the height changes, until the node reports itself synced.
+func followSync(addr func() (string, error), timeout time.Duration) error {
+	var deadline time.Time
+	if timeout != 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	last := -1
+	for {
+		a, err := addr()
+		if err == nil {
+			var status *Status
+			if status, err = statusFromRPC(a); err == nil {
+				if status.BlockHeight != last {
+					logger.Printf("Block %d =>\t\t\t%d peers, %s\n", status.BlockHeight, len(status.Peers), status.Sync)
+					last = status.BlockHeight
+				}
+				if status.Sync == "synced" {
+					return nil
+				}
+			}
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("The node has not caught up after %v. It keeps syncing; follow it with [eris chains status --watch].\nLast error =>\t\t\t%v", timeout, err)
+		}
+		logger.Debugf("Node not synced =>\t\t%v\n", err)
+		time.Sleep(statusWatchInterval)
+	}
+}
+
+// writeNodeConfig writes the default config.toml with the node's own
+// moniker and seeds.
+func writeNodeConfig(fileName, moniker, seeds string) error {
+	if err := ioutil.WriteFile(fileName, []byte(initialize.DefChainConfig()), 0644); err != nil {
+		return err
+	}
+	if err := util.SetTOMLKey(fileName, "", "moniker", strconv.Quote(moniker)); err != nil {
+		return err
+	}
+	return util.SetTOMLKey(fileName, "", "seeds", strconv.Quote(seeds))
+}
+
+func checkSeeds(seeds []string) error {
+	if len(seeds) == 0 {
+		return fmt.Errorf("Please give the marmots at least one seed with --seeds host:port.")
+	}
+	for _, seed := range seeds {
+		if _, _, err := net.SplitHostPort(seed); err != nil {
+			return fmt.Errorf("Bad seed (%s). Seeds should be host:port.", seed)
+		}
+	}
+	return nil
+}
+
+// the genesis file is either on disk or an IPFS hash, with or without
+// an ipfs: prefix
+func fetchGenesis(genesis, dir string) (string, error) {
+	if _, err := os.Stat(genesis); err == nil {
+		return genesis, nil
+	}
+
+	hash := strings.TrimPrefix(genesis, "ipfs:")
+	fileName := path.Join(dir, "genesis.json")
+	if err := ipfs.Node.Get(hash, fileName); err != nil {
+		return "", fmt.Errorf("The marmots could not find the genesis file (%s) on disk or in IPFS: %v", genesis, err)
+	}
+	return fileName, nil
+}
@@ -1,16 +1,17 @@
 package chains
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/data"
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/services"
@@ -28,17 +29,7 @@ func ImportChain(do *definitions.Do) error {
 	s := strings.Split(do.Path, ":")
 	if s[0] == "ipfs" {
 
-		var err error
-		if logger.Level > 0 {
-			err = util.GetFromIPFS(s[1], fileName, logger.Writer)
-		} else {
-			err = util.GetFromIPFS(s[1], fileName, bytes.NewBuffer([]byte{}))
-		}
-
-		if err != nil {
-			return err
-		}
-		return nil
+		return ipfs.Node.Get(s[1], fileName)
 	}
 
 	if strings.Contains(s[0], "github") {
@@ -162,64 +153,171 @@ func RenameChain(do *definitions.Do) error {
 	newNameBase := strings.Replace(do.NewName, filepath.Ext(do.NewName), "", 1)
 	transformOnly := newNameBase == do.Name
 
-	if isKnownChain(do.Name) {
-		logger.Infof("Renaming chain =>\t\t%s:%s\n", do.Name, do.NewName)
+	if !isKnownChain(do.Name) {
+		return fmt.Errorf("I cannot find that chain. Please check the chain name you sent me.")
+	}
+	if !transformOnly && isKnownChain(newNameBase) {
+		return fmt.Errorf("The marmots already know a chain called %s.", newNameBase)
+	}
+	logger.Infof("Renaming chain =>\t\t%s:%s\n", do.Name, do.NewName)
 
-		logger.Debugf("Loading Chain Def File =>\t%s\n", do.Name)
-		chainDef, err := loaders.LoadChainDefinition(do.Name, false, 1) // TODO:CNUM
-		if err != nil {
-			return err
-		}
+	// every container number of the chain is renamed below; the
+	// number loaded here does not matter for the definition file
+	logger.Debugf("Loading Chain Def File =>\t%s\n", do.Name)
+	chainDef, err := loaders.LoadChainDefinition(do.Name, false, 1)
+	if err != nil {
+		return err
+	}
 
-		if !transformOnly {
-			logger.Debugln("Embarking on DockerRename.")
-			err = perform.DockerRename(chainDef.Service, chainDef.Operations, do.Name, newNameBase)
-			if err != nil {
-				return err
-			}
-		}
+	oldFile := util.GetFileByNameAndType("chains", do.Name)
+	if filepath.Base(oldFile) == do.NewName {
+		logger.Infoln("Those are the same file. Not renaming")
+		return nil
+	}
+	oldRaw, err := ioutil.ReadFile(oldFile)
+	if err != nil {
+		return err
+	}
 
-		oldFile := util.GetFileByNameAndType("chains", do.Name)
-		if err != nil {
+	var newFile string
+	if filepath.Ext(do.NewName) == "" {
+		newFile = strings.Replace(oldFile, do.Name, do.NewName, 1)
+	} else {
+		newFile = filepath.Join(BlockchainsPath, do.NewName)
+	}
+
+	chainDef.Name = newNameBase
+	chainDef.Service.Name = ""
+	chainDef.Service.Image = ""
+
+	j := perform.NewJournal("Rename", do.DryRun)
+	if !transformOnly {
+		perform.AddRenameSteps(j, "chain", do.Name, newNameBase)
+	}
+	j.Add("write "+newFile, func() error {
+		return WriteChainDefinitionFile(chainDef, newFile)
+	}, &perform.Undo{Kind: perform.UndoRemovePath, Args: []string{newFile}})
+	j.Add("remove "+oldFile, func() error {
+		return os.Remove(oldFile)
+	}, &perform.Undo{Kind: perform.UndoWriteFile, Args: []string{oldFile, string(oldRaw)}})
+	return j.Run()
+}
+
+// CloneChain copies the chain do.Name, data and all, into a new chain
+// do.NewName. The new chain gets do.ChainID as its chain_id, in both
+// its definition and its genesis file, if given. The source is
+// stopped while its data is copied and restarted after.
+func CloneChain(do *definitions.Do) (err error) {
+	if do.Name == do.NewName {
+		return fmt.Errorf("Cannot clone to same name")
+	}
+	if isKnownChain(do.NewName) {
+		return fmt.Errorf("The chain (%s) already exists. Please pick a new name for the clone.", do.NewName)
+	}
+
+	src, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	typ, err := loaders.LoadChainType(src.ChainType)
+	if err != nil {
+		return err
+	}
+
+	srcFile := filepath.Join(BlockchainsPath, do.Name+".toml")
+	dstFile := filepath.Join(BlockchainsPath, do.NewName+".toml")
+	dstData := filepath.Join(DataContainersPath, do.NewName)
+	if _, err := os.Stat(srcFile); err != nil {
+		return fmt.Errorf("The marmots can only clone chains with a TOML definition: %v", err)
+	}
+
+	logger.Infof("Cloning chain =>\t\t%s:%s\n", do.Name, do.NewName)
+	if IsChainRunning(src) {
+		if err := perform.DockerStop(src.Service, src.Operations, 10); err != nil {
 			return err
 		}
+		defer func() {
+			start := definitions.NowDo()
+			start.Name = do.Name
+			start.Operations.ContainerNumber = src.Operations.ContainerNumber
+			if err2 := StartChain(start); err2 != nil && err == nil {
+				err = err2
+			}
+		}()
+	}
 
-		if filepath.Base(oldFile) == do.NewName {
-			logger.Infoln("Those are the same file. Not renaming")
-			return nil
-		}
+	// exported out of the way, so neither files left in the source's
+	// folder on the host leak into the clone nor is that folder touched
+	srcData, err := ioutil.TempDir("", "eris_clone_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(srcData)
 
-		logger.Debugln("Renaming Chain Definition File.")
-		var newFile string
-		if filepath.Ext(do.NewName) == "" {
-			newFile = strings.Replace(oldFile, do.Name, do.NewName, 1)
-		} else {
-			newFile = filepath.Join(BlockchainsPath, do.NewName)
-		}
+	export := definitions.NowDo()
+	export.Name = do.Name
+	export.Destination = srcData
+	export.Operations.ContainerNumber = src.Operations.ContainerNumber
+	if err := data.ExportData(export); err != nil {
+		return err
+	}
 
-		chainDef.Name = newNameBase
-		chainDef.Service.Name = ""
-		chainDef.Service.Image = ""
-		err = WriteChainDefinitionFile(chainDef, newFile)
+	// if something goes wrong, leave no half made clone behind
+	defer func() {
 		if err != nil {
+			logger.Infof("Error on cloneChain =>\t\t%v\n", err)
+			logger.Infoln("Cleaning up...")
+			clone := loaders.MockChainDefinition(do.NewName, do.NewName, false, 1)
+			perform.DockerRemove(clone.Service, clone.Operations, true)
+			os.RemoveAll(dstData)
+			os.Remove(dstFile)
+		}
+	}()
+
+	logger.Debugf("Copying chain data =>\t\t%s:%s\n", srcData, dstData)
+	os.RemoveAll(dstData)
+	if err = Copy(srcData, dstData); err != nil {
+		return err
+	}
+	chainDir := filepath.Join(dstData, typ.ChainsDir, do.Name)
+	if _, e := os.Stat(chainDir); e == nil {
+		newDir := filepath.Join(dstData, typ.ChainsDir, do.NewName)
+		if err = os.Rename(chainDir, newDir); err != nil {
 			return err
 		}
+		chainDir = newDir
+	}
 
-		if !transformOnly {
-			logger.Infof("Renaming DataC (fm ChainRaw) =>\t%s:%s\n", do.Name, do.NewName)
-			do.Operations.ContainerNumber = chainDef.Operations.ContainerNumber
-			logger.Debugf("\twith ContainerNumber =>\t%d\n", do.Operations.ContainerNumber)
-			err = data.RenameData(do)
-			if err != nil {
-				return err
-			}
+	chainID := do.ChainID
+	if chainID == "" {
+		chainID = src.ChainID
+	}
+	if chainID != src.ChainID {
+		if err = setGenesisChainID(filepath.Join(chainDir, "genesis.json"), chainID); err != nil {
+			return err
 		}
+	}
 
-		os.Remove(oldFile)
-	} else {
-		return fmt.Errorf("I cannot find that chain. Please check the chain name you sent me.")
+	imp := definitions.NowDo()
+	imp.Name = do.NewName
+	imp.Operations.ContainerNumber = 1
+	if err = data.ImportData(imp); err != nil {
+		return err
 	}
-	return nil
+
+	if err = Copy(srcFile, dstFile); err != nil {
+		return err
+	}
+	if err = setTOMLKey(dstFile, "name", do.NewName); err != nil {
+		return err
+	}
+	if err = setTOMLKey(dstFile, "chain_id", chainID); err != nil {
+		return err
+	}
+	if src.Ephemeral {
+		err = setTOMLKey(dstFile, "created", time.Now().UTC().Format(time.RFC3339))
+	}
+	return err
 }
 
 func UpdateChain(do *definitions.Do) error {
@@ -239,6 +337,46 @@ func UpdateChain(do *definitions.Do) error {
 	return nil
 }
 
+// MigrateChain moves the data of the chain do.Name out of its data
+// container into named volumes, and sets data_volumes and data_paths
+// in its definition so it stays there.
+func MigrateChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+
+	fileName := path.Join(BlockchainsPath, chain.Name+".toml")
+	old, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("The marmots can only migrate chains with TOML definitions (%s): %v", fileName, err)
+	}
+	// the volumes are mounted where the data container had its own
+	paths, err := perform.DataContainerPaths(chain.Operations)
+	if err != nil {
+		return err
+	}
+	if err := util.SetTOMLKey(fileName, "service", "data_volumes", "true"); err != nil {
+		return err
+	}
+	if err := util.SetTOMLKey(fileName, "service", "data_paths", util.TOMLStrings(paths)); err != nil {
+		ioutil.WriteFile(fileName, old, 0644)
+		return err
+	}
+
+	chain.Service.DataVolumes = true
+	chain.Service.DataPaths = paths
+	if err := perform.DockerMigrateData(chain.Service, chain.Operations, do.Timeout); err != nil {
+		if err2 := ioutil.WriteFile(fileName, old, 0644); err2 != nil {
+			logger.Errorf("The marmots could not put back %s: %v\n", fileName, err2)
+		}
+		return err
+	}
+	logger.Printf("Migrated =>\t\t\t%s\n", chain.Name)
+	do.Result = "success"
+	return nil
+}
+
 func RmChain(do *definitions.Do) error {
 	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
 	if err != nil {
@@ -273,10 +411,55 @@ func GraduateChain(do *definitions.Do) error {
 		return err
 	}
 
-	serv := loaders.ServiceDefFromChain(chain, loaders.ErisChainStart)
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return err
+	}
+
+	serv := loaders.ServiceDefFromChain(chain, typ.StartCmd)
 	if err := services.WriteServiceDefinitionFile(serv, path.Join(ServicesPath, chain.ChainID+".toml")); err != nil {
 		return err
 	}
+	logger.Printf("Graduated to service =>\t\t%s\n", chain.ChainID)
+	return nil
+}
+
+// DemoteService turns the service do.Name, usually one made by
+// GraduateChain, back into a chain which the chain tooling manages.
+// The chain keeps the service's data container. The service definition
+// is removed; an existing chain definition is only overwritten with
+// do.Force.
+func DemoteService(do *definitions.Do) error {
+	srv, err := loaders.LoadServiceDefinition(do.Name, false, 1)
+	if err != nil {
+		return err
+	}
+	if services.IsServiceRunning(srv.Service, srv.Operations) {
+		return fmt.Errorf("The service %s is running. Stop it first with [eris services stop %s].", do.Name, do.Name)
+	}
+
+	chain := loaders.ChainFromServiceDef(srv)
+	if _, err := loaders.LoadChainType(chain.ChainType); err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(BlockchainsPath, chain.Name) + ".toml"
+	if _, err := os.Stat(fileName); err == nil && !do.Force {
+		return fmt.Errorf("The marmots already know a chain called %s. Overwrite it with --force.", chain.Name)
+	}
+	if err := WriteChainDefinitionFile(chain, fileName); err != nil {
+		return err
+	}
+
+	// the service's stopped container is of no use to the chain
+	if err := perform.DockerRemove(srv.Service, srv.Operations, false); err != nil {
+		return err
+	}
+	if err := os.Remove(services.FindServiceDefinitionFile(do.Name)); err != nil {
+		return err
+	}
+
+	logger.Printf("Demoted to chain =>\t\t%s\n", chain.Name)
 	return nil
 }
 
@@ -294,17 +477,5 @@ func CatChain(do *definitions.Do) error {
 func exportFile(chainName string) (string, error) {
 	fileName := util.GetFileByNameAndType("chains", chainName)
 
-	var hash string
-	var err error
-	if logger.Level > 0 {
-		hash, err = util.SendToIPFS(fileName, logger.Writer)
-	} else {
-		hash, err = util.SendToIPFS(fileName, bytes.NewBuffer([]byte{}))
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	return hash, nil
+	return ipfs.Node.Add(fileName)
 }
@@ -8,11 +8,14 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/data"
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/keys"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/services"
 	"github.com/eris-ltd/eris-cli/util"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
@@ -21,6 +24,17 @@ import (
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/tcnksm/go-gitconfig"
 )
 
+func init() {
+	// services started with eris services start wait for their chains
+	services.WaitForChain = func(name string, cNum int) error {
+		chain, err := loaders.LoadChainDefinition(name, false, cNum)
+		if err != nil {
+			return err
+		}
+		return WaitForChain(chain, 1, DefaultWaitTimeout)
+	}
+}
+
 func NewChain(do *definitions.Do) error {
 	// read chainID from genesis. genesis may be in dir
 	// if no genesis or no genesis.chain_id, chainID = name
@@ -34,11 +48,36 @@ func NewChain(do *definitions.Do) error {
 		}
 	}
 	logger.Debugf("Starting Setup for ChnID =>\t%s\n", do.ChainID)
-	return setupChain(do, loaders.ErisChainNew)
+
+	typ, err := loaders.LoadChainType(do.ChainType)
+	if err != nil {
+		return err
+	}
+	if typ.NewCmd == "" {
+		return fmt.Errorf("The marmots do not know how to make new chains of type (%s).", typ.Name)
+	}
+	if do.Test && !do.Run {
+		return fmt.Errorf("The marmots can only test a chain they run. Please add --run.")
+	}
+	if err := setupChain(do, typ, typ.NewCmd); err != nil {
+		return err
+	}
+
+	if do.Test {
+		return checkChain(do.Name, do.Operations.ContainerNumber)
+	}
+	return nil
 }
 
 func InstallChain(do *definitions.Do) error {
-	return setupChain(do, loaders.ErisChainInstall)
+	typ, err := loaders.LoadChainType(do.ChainType)
+	if err != nil {
+		return err
+	}
+	if typ.InstallCmd == "" {
+		return fmt.Errorf("The marmots do not know how to install chains of type (%s).", typ.Name)
+	}
+	return setupChain(do, typ, typ.InstallCmd)
 }
 
 func StartChain(do *definitions.Do) error {
@@ -67,9 +106,14 @@ func StartChain(do *definitions.Do) error {
 		return nil
 	}
 
-	chain.Service.Command = loaders.ErisChainStart
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return err
+	}
+
+	chain.Service.Command = typ.StartCmd
 	if do.Run {
-		chain.Service.Command = loaders.ErisChainStartApi
+		chain.Service.Command = typ.APICmd
 	}
 	util.OverWriteOperations(chain.Operations, do.Operations)
 	chain.Service.Environment = append(chain.Service.Environment, "CHAIN_ID="+chain.ChainID)
@@ -115,9 +159,74 @@ func KillChain(do *definitions.Do) error {
 	return nil
 }
 
+// StatusChain queries the node of a running chain and reports its
+// block height, latest block time, validators, peers and sync state.
+// With do.Watch the report is refreshed until the process is killed.
+func StatusChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+
+	addr, err := ChainRPCAddress(chain)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Querying chain RPC =>\t\t%s:%s\n", chain.Name, addr)
+
+	for {
+		status, err := statusFromRPC(addr)
+		if err != nil {
+			return fmt.Errorf("The marmots could not get the status of %s from %s.\n%v", chain.Name, addr, err)
+		}
+		status.Name = chain.Name
+
+		if err := printStatus(status, do.ResultFormt); err != nil {
+			return err
+		}
+
+		if !do.Watch {
+			break
+		}
+		time.Sleep(statusWatchInterval)
+	}
+
+	return nil
+}
+
+// WaitChain blocks until the chain serves RPC requests and has reached
+// block do.Height, or until do.Timeout seconds have passed.
+func WaitChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+
+	return WaitForChain(chain, do.Height, time.Duration(do.Timeout)*time.Second)
+}
+
+// WaitForChain is the library form of eris chains wait. It is used by
+// flows which start a chain and then need it to be producing blocks.
+func WaitForChain(chain *definitions.Chain, height int, timeout time.Duration) error {
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return err
+	}
+	if typ.RPCPort == "" {
+		logger.Infof("Chain type has no RPC =>\t%s. Not waiting.\n", typ.Name)
+		return nil
+	}
+
+	logger.Infof("Waiting for chain =>\t\t%s:%d\n", chain.Name, height)
+	return waitForRPC(func() (string, error) {
+		return ChainRPCAddress(chain)
+	}, height, timeout)
+}
+
 func ThrowAwayChain(do *definitions.Do) error {
-	do.Name = do.Name + "_" + strings.Split(uuid.New(), "-")[0]
+	do.Name = freshThrowAwayName(do.Name, do.Operations.ContainerNumber)
 	do.Path = filepath.Join(ChainsConfigPath, "default")
+	do.Chain.Ephemeral = true
 	logger.Debugf("Making a ThrowAwayChain =>\t%s:%s\n", do.Name, do.Path)
 
 	if err := NewChain(do); err != nil {
@@ -138,11 +247,33 @@ func ThrowAwayChain(do *definitions.Do) error {
 	return nil
 }
 
+// freshThrowAwayName picks a name for a throwaway chain which nothing uses
+// yet, so whoever cleans up after it cannot remove somebody else's
+// files or containers.
+func freshThrowAwayName(name string, cNum int) string {
+	for {
+		tmp := name + "_" + strings.Split(uuid.New(), "-")[0]
+		taken := util.IsChainContainer(tmp, cNum, false) || util.IsDataContainer(tmp, cNum)
+		for _, p := range []string{
+			filepath.Join(BlockchainsPath, tmp+".toml"),
+			path.Join(DataContainersPath, tmp),
+			path.Join(ThrowAwayPath(), tmp),
+		} {
+			if _, err := os.Stat(p); err == nil {
+				taken = true
+			}
+		}
+		if !taken {
+			return tmp
+		}
+	}
+}
+
 //------------------------------------------------------------------------
 
 // the main function for setting up a chain container
 // handles both "new" and "fetch" - most of the differentiating logic is in the container
-func setupChain(do *definitions.Do, cmd string) (err error) {
+func setupChain(do *definitions.Do, typ *definitions.ChainType, cmd string) (err error) {
 	// XXX: if do.Name is unique, we can safely assume (and we probably should) that do.Operations.ContainerNumber = 1
 
 	// do.Name is mandatory
@@ -154,32 +285,60 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 		do.ChainID = do.Name
 	}
 
+	// if something goes wrong, undo exactly what was done so far
+	j := perform.NewJournal("Setup", false)
+	defer func() {
+		if err != nil {
+			logger.Infof("Error on setupChain =>\t\t%v\n", err)
+			logger.Infoln("Cleaning up...")
+			err = j.Rollback(err)
+			return
+		}
+		err = j.Commit()
+	}()
+
+	// a throwaway chain is recorded before anything else is made so
+	// gc can tell its remains from anybody else's
+	if do.Chain.Ephemeral || do.Chain.TTL != "" {
+		record := path.Join(ThrowAwayPath(), do.Name)
+		if _, statErr := os.Stat(record); os.IsNotExist(statErr) {
+			if err = j.Record("record "+do.Name+" as throwaway", &perform.Undo{Kind: perform.UndoRemovePath, Args: []string{record}}); err != nil {
+				return err
+			}
+		}
+		if err = os.MkdirAll(ThrowAwayPath(), 0755); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(record, []byte{}, 0644); err != nil {
+			return err
+		}
+	}
+
 	// do.Run containers and exit (creates data container)
 	if !data.IsKnown(containerName) {
-		if err := perform.DockerCreateDataContainer(do.Name, do.Operations.ContainerNumber); err != nil {
+		dataName := util.DataContainersName(do.Name, do.Operations.ContainerNumber)
+		if err = j.Record("create data container "+dataName, &perform.Undo{Kind: perform.UndoRemoveContainer, Args: []string{dataName}}); err != nil {
+			return err
+		}
+		if err = perform.DockerCreateDataContainer(do.Name, do.Operations.ContainerNumber); err != nil {
 			return fmt.Errorf("Error creating data containr =>\t%v", err)
 		}
 	}
 
 	logger.Debugf("Chain's Data Contain Built =>\t%s\n", do.Name)
 
-	// if something goes wrong, cleanup
-	defer func() {
-		if err != nil {
-			logger.Infof("Error on setupChain =>\t\t%v\n", err)
-			logger.Infoln("Cleaning up...")
-			if err2 := RmChain(do); err2 != nil {
-				err = fmt.Errorf("Tragic! Our marmots encountered an error during setupChain for %s.\nThey also failed to cleanup after themselves (remove containers) due to another error.\nFirst error =>\t\t\t%v\nCleanup error =>\t\t%v\n", containerName, err, err2)
-			}
-		}
-	}()
-
 	// copy do.Path, do.GenesisFile, config into container
-	containerDst := path.Join("blockchains", do.Name)           // path in container
+	containerDst := path.Join(typ.ChainsDir, do.Name)           // path in container
 	dst := path.Join(DataContainersPath, do.Name, containerDst) // path on host
 	// TODO: deal with do.Operations.ContainerNumbers ....!
 	// we probably need to update Import
 
+	hostDir := path.Join(DataContainersPath, do.Name)
+	if _, statErr := os.Stat(hostDir); os.IsNotExist(statErr) {
+		if err = j.Record("make "+hostDir, &perform.Undo{Kind: perform.UndoRemovePath, Args: []string{hostDir}}); err != nil {
+			return err
+		}
+	}
 	if err = os.MkdirAll(dst, 0700); err != nil {
 		return fmt.Errorf("Error making data directory: %v", err)
 	}
@@ -190,7 +349,7 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 		}
 	}
 	if do.GenesisFile != "" {
-		if err = Copy(do.GenesisFile, path.Join(dst, "genesis.json")); err != nil {
+		if err = Copy(do.GenesisFile, path.Join(dst, typ.GenesisFile)); err != nil {
 			return err
 		}
 	} else {
@@ -198,7 +357,22 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	}
 
 	if do.ConfigFile != "" {
-		if err = Copy(do.ConfigFile, path.Join(dst, "config."+path.Ext(do.ConfigFile))); err != nil {
+		configFile := typ.ConfigFile
+		if configFile == "" {
+			configFile = path.Base(do.ConfigFile)
+		}
+		if err = Copy(do.ConfigFile, path.Join(dst, configFile)); err != nil {
+			return err
+		}
+	}
+
+	// the node signs with a key from the keys service
+	if do.Key != "" {
+		var pv []byte
+		if pv, err = keys.ValidatorKey(do.Key); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(path.Join(dst, "priv_validator.json"), pv, 0600); err != nil {
 			return err
 		}
 	}
@@ -213,6 +387,15 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	}
 
 	chain := loaders.MockChainDefinition(do.Name, do.ChainID, false, do.Operations.ContainerNumber)
+	chain.ChainType = typ.Name
+	chain.ErisDBVersion = loaders.ImageVersion(typ.Image)
+	if do.Chain.Ephemeral || do.Chain.TTL != "" {
+		if chain.TTL, err = ttlOrDefault(do.Chain.TTL); err != nil {
+			return err
+		}
+		chain.Ephemeral = true
+		chain.Created = time.Now().UTC().Format(time.RFC3339)
+	}
 
 	//get maintainer info
 	uName, err := gitconfig.Username()
@@ -232,6 +415,9 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	// write the chain definition file ...
 	fileName := filepath.Join(BlockchainsPath, do.Name) + ".toml"
 	if _, err = os.Stat(fileName); err != nil {
+		if err = j.Record("write "+fileName, &perform.Undo{Kind: perform.UndoRemovePath, Args: []string{fileName}}); err != nil {
+			return err
+		}
 		if err = WriteChainDefinitionFile(chain, fileName); err != nil {
 			return fmt.Errorf("error writing chain definition to file: %v", err)
 		}
@@ -275,6 +461,15 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 
 	logger.Debugf("Starting chain via Docker =>\t%s\n", chain.Service.Name)
 	logger.Debugf("\twith Image =>\t\t%s\n", chain.Service.Image)
+	// a container which was already there is only stopped again
+	if _, exists := perform.ContainerExists(chain.Operations); !exists {
+		err = j.Record("run "+containerName, &perform.Undo{Kind: perform.UndoRemoveContainer, Args: []string{containerName}})
+	} else if _, running := perform.ContainerRunning(chain.Operations); !running {
+		err = j.Record("start "+containerName, &perform.Undo{Kind: perform.UndoStopContainer, Args: []string{containerName}})
+	}
+	if err != nil {
+		return err
+	}
 	err = perform.DockerRun(chain.Service, chain.Operations)
 	// this err is caught in the defer above
 
@@ -314,3 +509,33 @@ func getChainIDFromGenesis(genesis, name string) (string, error) {
 	}
 	return chainID, nil
 }
+
+func printStatus(status *Status, format string) error {
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		logger.Println(string(out))
+	case "":
+		logger.Printf("Chain =>\t\t\t%s:%s\n", status.Name, status.ChainID)
+		logger.Printf("RPC Address =>\t\t\t%s\n", status.RPCAddress)
+		logger.Printf("Block Height =>\t\t\t%d\n", status.BlockHeight)
+		if !status.BlockTime.IsZero() {
+			logger.Printf("Latest Block Time =>\t\t%s\n", status.BlockTime.Format(time.RFC3339))
+		}
+		logger.Printf("Sync =>\t\t\t\t%s\n", status.Sync)
+		logger.Printf("Validators =>\t\t\t%d\n", len(status.Validators))
+		for _, v := range status.Validators {
+			logger.Printf("\t%s\t%d\n", v.Address, v.VotingPower)
+		}
+		logger.Printf("Peers =>\t\t\t%d\n", len(status.Peers))
+		for _, p := range status.Peers {
+			logger.Printf("\t%s\t%s\toutbound=%v\n", p.Moniker, p.Host, p.IsOutbound)
+		}
+	default:
+		return fmt.Errorf("I do not know the output format (%s). Please use json or leave it blank.", format)
+	}
+	return nil
+}
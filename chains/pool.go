@@ -0,0 +1,259 @@
+package chains
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/code.google.com/p/go-uuid/uuid"
+)
+
+// pool chains are throwaway chains named pool_<uuid prefix>
+const poolPrefix = "pool"
+
+// ChainPoolPath holds an empty file for every idle pool chain.
+// A chain is leased by whoever manages to remove its file.
+func ChainPoolPath() string {
+	return path.Join(BlockchainsPath, "pool")
+}
+
+// chainLeasePath holds an empty file for every leased pool chain, made
+// when it was leased.
+func chainLeasePath() string {
+	return path.Join(ChainPoolPath(), "leased")
+}
+
+func IsPoolChain(name string) bool {
+	return strings.HasPrefix(name, poolPrefix+"_") && throwAwayName.MatchString(name)
+}
+
+// FillChainPool makes and starts throwaway chains until do.Size of
+// them are idle in the pool. Each chain's data is snapshotted before
+// it is first started so it can be reset after a lease, and each chain
+// is smoke tested before it goes into the pool.
+func FillChainPool(do *definitions.Do) error {
+	idle := idlePoolChains()
+	logger.Infof("Idle pool chains =>\t\t%d:%d\n", len(idle), do.Size)
+
+	for i := len(idle); i < do.Size; i++ {
+		name, err := addPoolChain()
+		if err != nil {
+			return err
+		}
+		logger.Printf("Added to pool =>\t\t%s\n", name)
+	}
+	return nil
+}
+
+// ListChainPool prints the idle pool chains.
+func ListChainPool(do *definitions.Do) error {
+	for _, name := range idlePoolChains() {
+		logger.Println(name)
+	}
+	return nil
+}
+
+// DrainChainPool removes every idle pool chain.
+func DrainChainPool(do *definitions.Do) error {
+	for _, name := range idlePoolChains() {
+		if !leasePoolChain(name) {
+			continue
+		}
+		logger.Printf("Removing =>\t\t\t%s\n", name)
+		if err := RemoveThrowAwayChain(name, definitions.BlankOperation()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LeasePoolChain takes an idle chain out of the pool and makes sure
+// it is running. It returns an empty name when the pool is empty.
+func LeasePoolChain() (string, error) {
+	for _, name := range idlePoolChains() {
+		if !leasePoolChain(name) {
+			continue // somebody else got it first
+		}
+
+		chain, err := loaders.LoadChainDefinition(name, false, 1)
+		if err != nil {
+			return "", err
+		}
+		if !IsChainRunning(chain) {
+			start := definitions.NowDo()
+			start.Name = name
+			start.Operations.ContainerNumber = 1
+			if err := StartChain(start); err != nil {
+				return "", err
+			}
+		}
+
+		logger.Infof("Leased pool chain =>\t\t%s\n", name)
+		return name, nil
+	}
+	return "", nil
+}
+
+// ReturnPoolChain hands back a leased chain. With reset the chain's
+// data is put back to its snapshot and the chain goes back into the
+// pool; otherwise it is removed. The pool is then topped up to size
+// in the background.
+func ReturnPoolChain(name string, reset bool, size int) error {
+	defer os.Remove(path.Join(chainLeasePath(), name))
+	if reset {
+		if err := resetPoolChain(name); err != nil {
+			logger.Infof("Could not reset =>\t\t%s: %v\n", name, err)
+			reset = false
+		}
+	}
+	if !reset {
+		if err := RemoveThrowAwayChain(name, definitions.BlankOperation()); err != nil {
+			return err
+		}
+	}
+
+	if len(idlePoolChains()) >= size {
+		return nil
+	}
+	return refillChainPool(size)
+}
+
+// refillChainPool tops the pool up to size in a detached eris process,
+// so whoever returned a chain does not wait for new ones to be made.
+func refillChainPool(size int) error {
+	// os.Args[0] is either a path or a name looked up in $PATH
+	self, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		self = os.Args[0]
+	}
+	cmd := exec.Command(self, "chains", "pool", "fill", "--size", strconv.Itoa(size))
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	logger.Infof("Refilling pool =>\t\t%d (pid %d)\n", size, cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+func addPoolChain() (name string, err error) {
+	do := definitions.NowDo()
+	do.Name = poolPrefix + "_" + strings.Split(uuid.New(), "-")[0]
+	do.Path = filepath.Join(ChainsConfigPath, "default")
+	do.Operations.ContainerNumber = 1
+	do.Chain.Ephemeral = true
+	name = do.Name
+
+	if err := NewChain(do); err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err != nil {
+			RemoveThrowAwayChain(name, definitions.BlankOperation())
+		}
+	}()
+
+	snapshot := definitions.NowDo()
+	snapshot.Name = name
+	snapshot.Operations.ContainerNumber = 1
+	if err = data.ExportData(snapshot); err != nil {
+		return "", err
+	}
+
+	start := definitions.NowDo()
+	start.Name = name
+	start.Operations.ContainerNumber = 1
+	if err = StartChain(start); err != nil {
+		return "", err
+	}
+	if err = checkChain(name, 1); err != nil {
+		return "", err
+	}
+
+	if err = os.MkdirAll(ChainPoolPath(), 0755); err != nil {
+		return "", err
+	}
+	return name, ioutil.WriteFile(path.Join(ChainPoolPath(), name), []byte{}, 0644)
+}
+
+// resetPoolChain swaps the chain's data container for a new one
+// holding the snapshot taken when the chain was made. A chain which
+// fails its smoke test after the reset is not put back.
+func resetPoolChain(name string) error {
+	chain, err := loaders.LoadChainDefinition(name, false, 1)
+	if err != nil {
+		return err
+	}
+
+	if IsChainRunning(chain) {
+		if err := perform.DockerStop(chain.Service, chain.Operations, 0); err != nil {
+			return err
+		}
+	}
+	if err := perform.DockerRemove(chain.Service, chain.Operations, true); err != nil {
+		return err
+	}
+
+	restore := definitions.NowDo()
+	restore.Name = name
+	restore.Operations.ContainerNumber = 1
+	if err := data.ImportData(restore); err != nil { // creates the data container
+		return err
+	}
+
+	start := definitions.NowDo()
+	start.Name = name
+	start.Operations.ContainerNumber = 1
+	if err := StartChain(start); err != nil {
+		return err
+	}
+	if err := checkChain(name, 1); err != nil {
+		return err
+	}
+
+	logger.Infof("Reset pool chain =>\t\t%s\n", name)
+	return ioutil.WriteFile(path.Join(ChainPoolPath(), name), []byte{}, 0644)
+}
+
+// idle pool chains whose definition is still around
+func idlePoolChains() []string {
+	idle := []string{}
+	files, _ := ioutil.ReadDir(ChainPoolPath())
+	for _, f := range files {
+		if IsPoolChain(f.Name()) && util.GetFileByNameAndType("chains", f.Name()) != "" {
+			idle = append(idle, f.Name())
+		}
+	}
+	return idle
+}
+
+func leasePoolChain(name string) bool {
+	if os.Remove(path.Join(ChainPoolPath(), name)) != nil {
+		return false
+	}
+	if err := os.MkdirAll(chainLeasePath(), 0755); err == nil {
+		ioutil.WriteFile(path.Join(chainLeasePath(), name), []byte{}, 0644)
+	}
+	return true
+}
+
+// leasedSince tells when a pool chain was leased, if it is.
+func leasedSince(name string) (time.Time, bool) {
+	info, err := os.Stat(path.Join(chainLeasePath(), name))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
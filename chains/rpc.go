@@ -0,0 +1,254 @@
+package chains
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+)
+
+// how long a single rpc call is allowed to take
+var rpcTimeout = 5 * time.Second
+
+// how often eris chains status --watch refreshes
+var statusWatchInterval = 2 * time.Second
+
+// how often a chain is polled while waiting for it
+var waitPollInterval = 500 * time.Millisecond
+
+// DefaultWaitTimeout is how long flows which boot a chain for their
+// own use wait for it to produce blocks before giving up.
+var DefaultWaitTimeout = 60 * time.Second
+
+// a chain whose latest block is older than this is reported
+// as catching up rather than synced
+var syncedThreshold = 2 * time.Minute
+
+// Status is the summary of a running chain as reported by its node.
+type Status struct {
+	Name        string       `json:"name"`
+	ChainID     string       `json:"chain_id"`
+	Moniker     string       `json:"moniker"`
+	Version     string       `json:"version"`
+	RPCAddress  string       `json:"rpc_address"`
+	BlockHeight int          `json:"block_height"`
+	BlockHash   string       `json:"block_hash"`
+	BlockTime   time.Time    `json:"block_time"`
+	Validators  []*Validator `json:"validators"`
+	Peers       []*Peer      `json:"peers"`
+	Sync        string       `json:"sync"`
+}
+
+type Validator struct {
+	Address     string `json:"address"`
+	VotingPower int64  `json:"voting_power"`
+}
+
+type Peer struct {
+	Moniker    string `json:"moniker"`
+	Host       string `json:"host"`
+	IsOutbound bool   `json:"is_outbound"`
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      string          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   string          `json:"error"`
+}
+
+type rpcNodeInfo struct {
+	Moniker string `json:"moniker"`
+	Network string `json:"network"`
+	Version string `json:"version"`
+	Host    string `json:"host"`
+}
+
+type rpcStatus struct {
+	NodeInfo          rpcNodeInfo `json:"node_info"`
+	LatestBlockHash   string      `json:"latest_block_hash"`
+	LatestBlockHeight int         `json:"latest_block_height"`
+	LatestBlockTime   int64       `json:"latest_block_time"` // nanoseconds
+}
+
+type rpcNetInfo struct {
+	Peers []struct {
+		rpcNodeInfo `json:"node_info"`
+		IsOutbound  bool `json:"is_outbound"`
+	} `json:"peers"`
+}
+
+type rpcValidators struct {
+	BondedValidators []struct {
+		Address     string `json:"address"`
+		VotingPower int64  `json:"voting_power"`
+	} `json:"bonded_validators"`
+}
+
+// ChainRPCAddress finds the host:port where the rpc server of a
+// chain's container can be reached. Published ports are preferred
+// over the container's own address on the docker bridge.
+func ChainRPCAddress(chain *definitions.Chain) (string, error) {
+	if !IsChainRunning(chain) {
+		return "", fmt.Errorf("The chain (%s) is not running.\nStart it with: [eris chains start %s]", chain.Name, chain.Name)
+	}
+
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return "", err
+	}
+	if typ.RPCPort == "" {
+		return "", fmt.Errorf("Chains of type (%s) do not have an RPC server the marmots can talk to.", typ.Name)
+	}
+
+	cont, err := util.DockerClient.InspectContainer(chain.Operations.SrvContainerID)
+	if err != nil {
+		return "", err
+	}
+
+	return util.ContainerAddress(cont, typ.RPCPort)
+}
+
+// rpcCall performs a single JSON-RPC request against the node at
+// addr and unmarshals the result field into result.
+func rpcCall(addr, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      "eris-cli",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: rpcTimeout}
+	response, err := client.Post("http://"+addr+"/", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	raw, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	var res rpcResponse
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return fmt.Errorf("The marmots could not understand the RPC response to %s (status %d): %v", method, response.StatusCode, err)
+	}
+	if res.Error != "" {
+		return fmt.Errorf("RPC error on %s: %s", method, res.Error)
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("RPC error on %s: %s", method, response.Status)
+	}
+
+	// some versions of the node wrap results as [type_byte, result]
+	if len(res.Result) != 0 && res.Result[0] == '[' {
+		var wrapped []json.RawMessage
+		if err := json.Unmarshal(res.Result, &wrapped); err == nil && len(wrapped) == 2 {
+			res.Result = wrapped[1]
+		}
+	}
+
+	return json.Unmarshal(res.Result, result)
+}
+
+// statusFromRPC gathers the status, validator set, and peers of
+// the node at addr.
+func statusFromRPC(addr string) (*Status, error) {
+	var st rpcStatus
+	if err := rpcCall(addr, "status", &st); err != nil {
+		return nil, err
+	}
+
+	var vals rpcValidators
+	if err := rpcCall(addr, "list_validators", &vals); err != nil {
+		return nil, err
+	}
+
+	var netInfo rpcNetInfo
+	if err := rpcCall(addr, "net_info", &netInfo); err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		ChainID:     st.NodeInfo.Network,
+		Moniker:     st.NodeInfo.Moniker,
+		Version:     st.NodeInfo.Version,
+		RPCAddress:  addr,
+		BlockHeight: st.LatestBlockHeight,
+		BlockHash:   st.LatestBlockHash,
+		Validators:  []*Validator{},
+		Peers:       []*Peer{},
+	}
+	if st.LatestBlockTime != 0 {
+		status.BlockTime = time.Unix(0, st.LatestBlockTime).UTC()
+	}
+
+	for _, v := range vals.BondedValidators {
+		status.Validators = append(status.Validators, &Validator{Address: v.Address, VotingPower: v.VotingPower})
+	}
+	for _, p := range netInfo.Peers {
+		status.Peers = append(status.Peers, &Peer{Moniker: p.Moniker, Host: p.Host, IsOutbound: p.IsOutbound})
+	}
+
+	status.Sync = syncState(status, time.Now())
+	return status, nil
+}
+
+// waitForRPC polls the node found by addr until it serves requests
+// and has reached at least the given block height. addr is resolved on
+// every attempt as the container may still be coming up.
+func waitForRPC(addr func() (string, error), height int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		a, err := addr()
+		if err == nil {
+			var st rpcStatus
+			if err = rpcCall(a, "status", &st); err == nil {
+				if st.LatestBlockHeight >= height {
+					logger.Debugf("Chain reached height =>\t%d:%d\n", st.LatestBlockHeight, height)
+					return nil
+				}
+				err = fmt.Errorf("the chain is at block %d", st.LatestBlockHeight)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("The marmots gave up waiting after %v for the chain to reach block %d.\nLast error =>\t\t\t%v", timeout, height, err)
+		}
+		logger.Debugf("Chain not ready =>\t\t%v\n", err)
+		time.Sleep(waitPollInterval)
+	}
+}
+
+func syncState(status *Status, now time.Time) string {
+	switch {
+	case status.BlockHeight == 0:
+		return "waiting for first block"
+	case now.Sub(status.BlockTime) > syncedThreshold:
+		return "catching up"
+	default:
+		return "synced"
+	}
+}
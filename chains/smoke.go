@@ -0,0 +1,406 @@
+package chains
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
+)
+
+// how long an "increases" check waits when the spec does not say
+var defaultSmokeWithin = 30 * time.Second
+
+// SmokeSpec is a list of RPC calls eris chains test runs against a
+// chain, with what to expect of each result. Specs are TOML:
+//
+//	name = "validators"
+//
+//	[[check]]
+//	name = "four validators"
+//	method = "list_validators"
+//	field = "bonded_validators.#"
+//	op = "eq"
+//	value = 4
+type SmokeSpec struct {
+	Name   string        `toml:"name"`
+	Checks []*SmokeCheck `toml:"check"`
+}
+
+// SmokeCheck calls Method with Params and looks up Field in the result.
+// Field is dotted; numbers index lists and # is the length of a list or
+// object. The value found is compared to Value with Op, one of eq, ne,
+// gt, ge, lt, le, exists, or increases. An increases check calls Method
+// again until the value grows, for up to Within (30s by default).
+type SmokeCheck struct {
+	Name   string        `toml:"name"`
+	Method string        `toml:"method"`
+	Params []interface{} `toml:"params"`
+	Field  string        `toml:"field"`
+	Op     string        `toml:"op"`
+	Value  interface{}   `toml:"value"`
+	Within string        `toml:"within"`
+}
+
+// SmokeResult is the outcome of one check. Failure is set when the
+// check ran and the result was wrong, Error when the check could not
+// run at all.
+type SmokeResult struct {
+	Check   *SmokeCheck
+	Elapsed time.Duration
+	Failure string
+	Error   string
+}
+
+func (r *SmokeResult) Passed() bool {
+	return r.Failure == "" && r.Error == ""
+}
+
+// DefaultSmokeSpec checks a chain is up: it has the right chain_id,
+// has validators, and makes blocks.
+func DefaultSmokeSpec(chainID string) *SmokeSpec {
+	return &SmokeSpec{
+		Name: "default",
+		Checks: []*SmokeCheck{
+			{Name: "chain id", Method: "status", Field: "node_info.network", Op: "eq", Value: chainID},
+			{Name: "has validators", Method: "list_validators", Field: "bonded_validators.#", Op: "ge", Value: 1},
+			{Name: "block height increases", Method: "status", Field: "latest_block_height", Op: "increases"},
+		},
+	}
+}
+
+func LoadSmokeSpec(fileName string) (*SmokeSpec, error) {
+	spec := &SmokeSpec{}
+	if _, err := toml.DecodeFile(fileName, spec); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the test spec (%s): %v", fileName, err)
+	}
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	}
+	for i, check := range spec.Checks {
+		if check.Method == "" {
+			return nil, fmt.Errorf("Check %d of the test spec has no method.", i+1)
+		}
+		if check.Name == "" {
+			check.Name = fmt.Sprintf("%s %s %s", check.Method, check.Field, check.Op)
+		}
+	}
+	return spec, nil
+}
+
+// SmokeTestChain runs the checks of the spec do.ConfigFile (or the
+// default spec) against the running chain do.Name. With do.Path the
+// results are also written there as JUnit XML.
+func SmokeTestChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+
+	spec := DefaultSmokeSpec(chain.ChainID)
+	if do.ConfigFile != "" {
+		if spec, err = LoadSmokeSpec(do.ConfigFile); err != nil {
+			return err
+		}
+	}
+
+	addr, err := ChainRPCAddress(chain)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Testing chain =>\t\t%s:%s\n", chain.Name, spec.Name)
+	started := time.Now()
+	results := runSmokeSpec(addr, spec)
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Passed():
+			logger.Printf("PASS =>\t\t\t\t%s\n", r.Check.Name)
+		case r.Error != "":
+			failed++
+			logger.Printf("ERROR =>\t\t\t%s: %s\n", r.Check.Name, r.Error)
+		default:
+			failed++
+			logger.Printf("FAIL =>\t\t\t\t%s: %s\n", r.Check.Name, r.Failure)
+		}
+	}
+
+	if do.Path != "" {
+		if err := writeJUnit(do.Path, chain.Name, spec, results, started); err != nil {
+			return err
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d checks failed on %s.", failed, len(results), chain.Name)
+	}
+	do.Result = "success"
+	return nil
+}
+
+// checkChain runs the default checks against a chain eris has just
+// started, so a chain which does not come up is not handed out. Chains
+// without an RPC server cannot be checked and pass.
+func checkChain(name string, containerNumber int) error {
+	chain, err := loaders.LoadChainDefinition(name, false, containerNumber)
+	if err != nil {
+		return err
+	}
+	typ, err := loaders.LoadChainType(chain.ChainType)
+	if err != nil {
+		return err
+	}
+	if typ.RPCPort == "" {
+		logger.Infof("Chain type has no RPC =>\t%s. Not testing.\n", typ.Name)
+		return nil
+	}
+
+	check := definitions.NowDo()
+	check.Name = name
+	check.Operations.ContainerNumber = containerNumber
+	if err := SmokeTestChain(check); err != nil {
+		return fmt.Errorf("The chain %s failed its smoke test: %v", name, err)
+	}
+	return nil
+}
+
+func runSmokeSpec(addr string, spec *SmokeSpec) []*SmokeResult {
+	results := []*SmokeResult{}
+	for _, check := range spec.Checks {
+		started := time.Now()
+		r := runSmokeCheck(addr, check)
+		r.Elapsed = time.Since(started)
+		results = append(results, r)
+	}
+	return results
+}
+
+func runSmokeCheck(addr string, check *SmokeCheck) *SmokeResult {
+	r := &SmokeResult{Check: check}
+
+	first, err := smokeField(addr, check)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	switch check.Op {
+	case "exists":
+	case "increases":
+		within := defaultSmokeWithin
+		if check.Within != "" {
+			if within, err = time.ParseDuration(check.Within); err != nil {
+				r.Error = fmt.Sprintf("bad within (%s): %v", check.Within, err)
+				return r
+			}
+		}
+		r.Failure = smokeIncreases(addr, check, first, within)
+	default:
+		r.Failure, err = smokeCompare(check.Op, first, check.Value)
+		if err != nil {
+			r.Error = err.Error()
+		}
+	}
+	return r
+}
+
+func smokeIncreases(addr string, check *SmokeCheck, first interface{}, within time.Duration) string {
+	start, ok := smokeNumber(first)
+	if !ok {
+		return fmt.Sprintf("%s is not a number: %v", check.Field, first)
+	}
+
+	deadline := time.Now().Add(within)
+	for time.Now().Before(deadline) {
+		time.Sleep(waitPollInterval)
+		value, err := smokeField(addr, check)
+		if err != nil {
+			logger.Debugf("Check call failed =>\t%v\n", err)
+			continue
+		}
+		if now, ok := smokeNumber(value); ok && now > start {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s stayed at %v for %v", check.Field, first, within)
+}
+
+func smokeField(addr string, check *SmokeCheck) (interface{}, error) {
+	var result interface{}
+	if err := rpcCall(addr, check.Method, &result, check.Params...); err != nil {
+		return nil, err
+	}
+	return lookupField(result, check.Field)
+}
+
+// lookupField walks a dotted path through decoded JSON.
+func lookupField(value interface{}, field string) (interface{}, error) {
+	if field == "" {
+		return value, nil
+	}
+
+	for _, part := range strings.Split(field, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if part == "#" {
+				value = float64(len(v))
+				continue
+			}
+			next, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("no field %s in the result", field)
+			}
+			value = next
+		case []interface{}:
+			if part == "#" {
+				value = float64(len(v))
+				continue
+			}
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no element %s of %s in the result", part, field)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("no field %s in the result", field)
+		}
+	}
+	return value, nil
+}
+
+// smokeCompare returns why got fails the check against want, or "" if
+// it passes.
+func smokeCompare(op string, got, want interface{}) (string, error) {
+	g, gNum := smokeNumber(got)
+	w, wNum := smokeNumber(want)
+
+	switch op {
+	case "eq", "ne":
+		equal := fmt.Sprint(got) == fmt.Sprint(want)
+		if gNum && wNum {
+			equal = g == w
+		}
+		if equal == (op == "eq") {
+			return "", nil
+		}
+		return fmt.Sprintf("expected %s %v, got %v", op, want, got), nil
+	case "gt", "ge", "lt", "le":
+		if !gNum || !wNum {
+			return "", fmt.Errorf("%s needs numbers, got %v and %v", op, got, want)
+		}
+		var pass bool
+		switch op {
+		case "gt":
+			pass = g > w
+		case "ge":
+			pass = g >= w
+		case "lt":
+			pass = g < w
+		case "le":
+			pass = g <= w
+		}
+		if pass {
+			return "", nil
+		}
+		return fmt.Sprintf("expected %s %v, got %v", op, want, got), nil
+	}
+	return "", fmt.Errorf("unknown op (%s). Use eq, ne, gt, ge, lt, le, exists or increases", op)
+}
+
+// numbers come out of JSON as float64, out of TOML as int64, and
+// balances are sometimes strings
+func smokeNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitReport(chainName string, spec *SmokeSpec, results []*SmokeResult, started time.Time) *junitSuites {
+	suite := junitSuite{
+		Name:      chainName + "." + spec.Name,
+		Tests:     len(results),
+		Time:      junitSeconds(time.Since(started)),
+		Timestamp: started.UTC().Format("2006-01-02T15:04:05"),
+		Cases:     []junitCase{},
+	}
+
+	for _, r := range results {
+		c := junitCase{
+			ClassName: suite.Name,
+			Name:      r.Check.Name,
+			Time:      junitSeconds(r.Elapsed),
+		}
+		if r.Error != "" {
+			suite.Errors++
+			c.Error = &junitMessage{Message: r.Error, Text: r.Check.Method}
+		} else if r.Failure != "" {
+			suite.Failures++
+			c.Failure = &junitMessage{Message: r.Failure, Text: r.Check.Method + " " + r.Check.Field}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	return &junitSuites{Suites: []junitSuite{suite}}
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeJUnit(fileName, chainName string, spec *SmokeSpec, results []*SmokeResult, started time.Time) error {
+	raw, err := xml.MarshalIndent(junitReport(chainName, spec, results, started), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	logger.Infof("Writing JUnit results =>\t%s\n", fileName)
+	return ioutil.WriteFile(fileName, append([]byte(xml.Header), raw...), 0644)
+}
@@ -0,0 +1,153 @@
+package chains
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// UpgradeChain moves a chain to the node image version do.Version.
+// The chain's data is snapshotted before anything changes and the
+// snapshot is kept. If the upgraded node does not come up and reach
+// the block height the chain was at within do.Timeout seconds and pass
+// its smoke test, the old container, data, and erisdb_version are
+// restored.
+func UpgradeChain(do *definitions.Do) (err error) {
+	if do.Version == "" {
+		return fmt.Errorf("Please tell the marmots which version to upgrade to with --to.")
+	}
+
+	chain, err := loaders.LoadChainDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+
+	fileName := path.Join(BlockchainsPath, chain.Name+".toml")
+	oldVersion := chain.ErisDBVersion
+	oldImage := chain.Service.Image
+	newImage := loaders.PinImage(oldImage, do.Version)
+	if newImage == oldImage {
+		logger.Infof("Chain already pinned to =>\t%s\n", newImage)
+		return nil
+	}
+
+	wasRunning := IsChainRunning(chain)
+	height := 1
+	if wasRunning {
+		if addr, err := ChainRPCAddress(chain); err == nil {
+			if status, err := statusFromRPC(addr); err == nil && status.BlockHeight > height {
+				height = status.BlockHeight
+			}
+		}
+	}
+	logger.Infof("Upgrading chain =>\t\t%s:%s\n", chain.Name, newImage)
+	logger.Debugf("\tfrom Image =>\t\t%s\n", oldImage)
+	logger.Debugf("\thealthy at Height =>\t%d\n", height)
+
+	// the data must not change underneath the snapshot
+	if wasRunning {
+		if err := perform.DockerStop(chain.Service, chain.Operations, 10); err != nil {
+			return err
+		}
+	}
+
+	snapshot := definitions.NowDo()
+	snapshot.Name = chain.Name
+	snapshot.Operations = chain.Operations
+	snapshot.Tag = "upgrade-" + time.Now().UTC().Format("20060102T150405Z")
+	logger.Infof("Snapshotting chain data =>\t%s:%s\n", chain.Name, snapshot.Tag)
+	if err := data.SnapshotData(snapshot); err != nil {
+		return fmt.Errorf("The marmots could not snapshot the chain's data, nothing was changed: %v", err)
+	}
+
+	if err := perform.DockerPullImage(newImage); err != nil {
+		return fmt.Errorf("The marmots could not pull %s, nothing was changed: %v", newImage, err)
+	}
+
+	defer func() {
+		if err != nil {
+			logger.Infof("Error on upgradeChain =>\t%v\n", err)
+			logger.Infof("Rolling back to =>\t\t%s\n", oldImage)
+			if err2 := rollbackUpgrade(do, chain, fileName, oldVersion, snapshot.Tag, wasRunning); err2 != nil {
+				err = fmt.Errorf("Tragic! The upgrade of %s failed and so did the rollback.\nUpgrade error =>\t\t%v\nRollback error =>\t\t%v\nThe data snapshot is =>\t\t%s:%s\n", chain.Name, err, err2, chain.Name, snapshot.Tag)
+			}
+		}
+	}()
+
+	if err = setTOMLKey(fileName, "erisdb_version", do.Version); err != nil {
+		return err
+	}
+	if err = recreateChain(do, chain); err != nil {
+		return err
+	}
+	if err = WaitForChain(chain, height, time.Duration(do.Timeout)*time.Second); err != nil {
+		return fmt.Errorf("The upgraded node failed its health check: %v", err)
+	}
+	if err = checkChain(chain.Name, chain.Operations.ContainerNumber); err != nil {
+		return err
+	}
+
+	if !wasRunning {
+		err = perform.DockerStop(chain.Service, chain.Operations, 10)
+	}
+	return err
+}
+
+// rollbackUpgrade puts back the chain as it was before UpgradeChain:
+// the data exactly as in the snapshot tag, the old erisdb_version, and
+// a container of the old image, which must pass its smoke test and is
+// left running only if it was before.
+func rollbackUpgrade(do *definitions.Do, chain *definitions.Chain, fileName, oldVersion, tag string, wasRunning bool) error {
+	if IsChainRunning(chain) {
+		if err := perform.DockerStop(chain.Service, chain.Operations, 10); err != nil {
+			return err
+		}
+	}
+
+	restore := definitions.NowDo()
+	restore.Name = chain.Name
+	restore.Operations = chain.Operations
+	restore.Tag = tag
+	restore.Force = true // the chain was stopped above
+	if err := data.RollbackData(restore); err != nil {
+		return err
+	}
+
+	if err := setTOMLKey(fileName, "erisdb_version", oldVersion); err != nil {
+		return err
+	}
+
+	if err := recreateChain(do, chain); err != nil {
+		return err
+	}
+	if err := checkChain(chain.Name, chain.Operations.ContainerNumber); err != nil {
+		return err
+	}
+	if !wasRunning {
+		return perform.DockerStop(chain.Service, chain.Operations, 10)
+	}
+	return nil
+}
+
+// recreateChain removes the chain's container and starts a new
+// one from the (re)loaded chain definition.
+func recreateChain(do *definitions.Do, chain *definitions.Chain) error {
+	if IsChainExisting(chain) {
+		if err := perform.DockerRemove(chain.Service, chain.Operations, false); err != nil {
+			return err
+		}
+	}
+
+	start := definitions.NowDo()
+	start.Name = chain.Name
+	start.Operations.ContainerNumber = chain.Operations.ContainerNumber
+	start.Operations.PublishAllPorts = do.Operations.PublishAllPorts
+	return StartChain(start)
+}
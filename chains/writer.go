@@ -1,11 +1,16 @@
 package chains
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/util"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 
@@ -50,10 +55,57 @@ func WriteChainDefinitionFile(chainDef *def.Chain, fileName string) error {
 		enc.Indent = ""
 		writer.Write([]byte("name = \"" + chainDef.Name + "\"\n"))
 		writer.Write([]byte("chain_id = \"" + chainDef.ChainID + "\"\n"))
+		if chainDef.ChainType != "" {
+			writer.Write([]byte("chain_type = \"" + chainDef.ChainType + "\"\n"))
+		}
+		if chainDef.ErisDBVersion != "" {
+			writer.Write([]byte("erisdb_version = \"" + chainDef.ErisDBVersion + "\"\n"))
+		}
+		if chainDef.Ephemeral {
+			writer.Write([]byte("ephemeral = true\n"))
+			writer.Write([]byte("created = \"" + chainDef.Created + "\"\n"))
+			writer.Write([]byte("ttl = \"" + chainDef.TTL + "\"\n"))
+		}
 		writer.Write([]byte("\n[service]\n"))
 		enc.Encode(chainDef.Service)
 		writer.Write([]byte("\n[maintainer]\n"))
 		enc.Encode(chainDef.Maintainer)
+		writer.Write([]byte("\n[location]\n"))
+		enc.Encode(chainDef.Location)
+		writer.Write([]byte("\n[machine]\n"))
+		enc.Encode(chainDef.Machine)
 	}
 	return nil
 }
+
+// setTOMLKey sets a top level string key of a TOML chain definition
+// in place, leaving the rest of the file as the user wrote it.
+func setTOMLKey(fileName, key, value string) error {
+	if filepath.Ext(fileName) != ".toml" {
+		return fmt.Errorf("The marmots can only update TOML chain definitions in place, not (%s).", fileName)
+	}
+
+	return util.SetTOMLKey(fileName, "", key, strconv.Quote(value))
+}
+
+// setGenesisChainID changes the chain_id in a genesis file, keeping
+// every other field (and the exact value of every number) as it was.
+func setGenesisChainID(fileName, chainID string) error {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("The marmots could not read the genesis file to change its chain_id: %v", err)
+	}
+
+	genesis := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&genesis); err != nil {
+		return fmt.Errorf("Error reading genesis file %s: %v", fileName, err)
+	}
+	genesis["chain_id"] = chainID
+
+	if raw, err = json.MarshalIndent(genesis, "", "  "); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, append(raw, '\n'), 0644)
+}
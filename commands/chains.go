@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	chns "github.com/eris-ltd/eris-cli/chains"
+	"github.com/eris-ltd/eris-cli/util"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
@@ -46,18 +47,28 @@ away!`,
 func buildChainsCommand() {
 	Chains.AddCommand(chainsNew)
 	Chains.AddCommand(chainsInstall)
+	Chains.AddCommand(chainsJoin)
 	Chains.AddCommand(chainsImport)
 	Chains.AddCommand(chainsListKnown)
 	Chains.AddCommand(chainsList)
 	Chains.AddCommand(chainsEdit)
+	Chains.AddCommand(chainsConfig)
 	Chains.AddCommand(chainsStart)
 	Chains.AddCommand(chainsLogs)
 	Chains.AddCommand(chainsListRunning)
 	Chains.AddCommand(chainsInspect)
+	Chains.AddCommand(chainsStatus)
+	Chains.AddCommand(chainsWait)
+	Chains.AddCommand(chainsTest)
+	Chains.AddCommand(chainsGC)
+	Chains.AddCommand(chainsClone)
+	buildChainsPoolCommand()
+	Chains.AddCommand(chainsPool)
 	Chains.AddCommand(chainsStop)
 	Chains.AddCommand(chainsExport)
 	Chains.AddCommand(chainsRename)
 	Chains.AddCommand(chainsUpdate)
+	Chains.AddCommand(chainsUpgrade)
 	Chains.AddCommand(chainsRemove)
 	Chains.AddCommand(chainsGraduate)
 	Chains.AddCommand(chainsCat)
@@ -71,6 +82,10 @@ var chainsNew = &cobra.Command{
 	Long: `Hashes a new blockchain.
 
 Will use a default genesis.json unless a --genesis flag is passed.
+With --key the node signs with that key from the keys service (see
+[eris keys ls]) instead of the default priv_validator.json.
+With --run --test the new chain is checked as [eris chains test]
+does: it has the right chain id, has validators and makes blocks.
 Still a WIP.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		NewChain(cmd, args)
@@ -90,6 +105,21 @@ Still a WIP.`,
 	},
 }
 
+var chainsJoin = &cobra.Command{
+	Use:   "join [name]",
+	Short: "Join an existing network.",
+	Long: `Join an existing network.
+
+Sets up a node from the network's genesis file, writes the default
+config.toml with the given seeds and moniker, starts it and reports its
+sync progress until it has caught up with the network.`,
+	Example: `  eris chains join staging --genesis genesis.json --seeds 10.0.0.1:46656,10.0.0.2:46656
+  eris chains join staging --genesis QmTg7R... --seeds seed.example.com:46656 --moniker my-node`,
+	Run: func(cmd *cobra.Command, args []string) {
+		JoinChain(cmd, args)
+	},
+}
+
 var chainsListKnown = &cobra.Command{
 	Use:   "known",
 	Short: "List all the blockchains Eris knows about.",
@@ -154,6 +184,24 @@ Edit will utilize your default editor.
 	},
 }
 
+var chainsConfig = &cobra.Command{
+	Use:   "config [name] get|set|edit [key] [value]",
+	Short: "Read and change the node config of a blockchain.",
+	Long: `Read and change the config.toml of a blockchain's node.
+
+The config is read from and written back to the chain's data
+container. The values of moniker, seeds, log_level, db_backend
+and rpc_laddr are checked before they are saved. When the chain
+is running you are offered a restart so the change takes effect.`,
+	Example: `  eris chains config simplechain get -> will print the whole config
+  eris chains config simplechain get seeds
+  eris chains config simplechain set log_level info
+  eris chains config simplechain edit -> will open the config in $EDITOR`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ConfigChain(cmd, args)
+	},
+}
+
 var chainsStart = &cobra.Command{
 	Use:   "start",
 	Short: "Start a blockchain.",
@@ -213,6 +261,150 @@ see: https://github.com/fsouza/go-dockerclient/blob/master/container.go#L235`,
 	},
 }
 
+var chainsStatus = &cobra.Command{
+	Use:   "status [name]",
+	Short: "Display the status of a running blockchain.",
+	Long: `Display the status of a running blockchain.
+
+Queries the chain's RPC server and reports the block height, the
+time of the latest block, the validator set, the connected peers,
+and whether the node is in sync.
+
+Use --watch to keep refreshing the report and --format json for
+machine readable output.`,
+	Example: `  eris chains status 2gather -> will display the status of 2gather once
+  eris chains status 2gather --watch -> will refresh the status every few seconds
+  eris chains status 2gather --format json -> will display the status as json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		StatusChain(cmd, args)
+	},
+}
+
+var chainsWait = &cobra.Command{
+	Use:   "wait [name]",
+	Short: "Wait for a blockchain to be ready.",
+	Long: `Wait for a blockchain to be ready.
+
+Blocks until the chain's RPC server answers requests and the chain
+has reached the given block height. Exits with an error if that
+does not happen before the timeout.`,
+	Example: `  eris chains wait 2gather -> will wait for 2gather to produce its first block
+  eris chains wait 2gather --height 10 --timeout 120 -> will wait up to two minutes for block 10`,
+	Run: func(cmd *cobra.Command, args []string) {
+		WaitChain(cmd, args)
+	},
+}
+
+var chainsTest = &cobra.Command{
+	Use:   "test [name] [spec]",
+	Short: "Run RPC checks against a running blockchain.",
+	Long: `Run RPC checks against a running blockchain.
+
+The spec is a TOML file listing RPC calls to make and what to expect
+of their results, for example that the block height increases, that
+an account has a given balance, or that there are four validators:
+
+  [[check]]
+  name = "four validators"
+  method = "list_validators"
+  field = "bonded_validators.#"
+  op = "eq"
+  value = 4
+
+Field is a dotted path into the result; numbers index lists and #
+is the length of a list. Op is one of eq, ne, gt, ge, lt, le, exists
+or increases. Without a spec the chain's id, validators and block
+production are checked.
+
+Use --junit to also write the results as JUnit XML for CI.`,
+	Example: `  eris chains test 2gather -> will check 2gather is up and making blocks
+  eris chains test 2gather smoke.toml --junit results.xml -> will run the checks in smoke.toml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SmokeTestChain(cmd, args)
+	},
+}
+
+var chainsClone = &cobra.Command{
+	Use:   "clone [src] [dst]",
+	Short: "Copy a blockchain, data and all, into a new chain.",
+	Long: `Copy a blockchain, data and all, into a new chain.
+
+The contents of the source chain's data container are copied into a
+new data container and a definition file is written for the new chain.
+The source chain is stopped while its data is copied and restarted
+afterwards; otherwise it is left as it was.`,
+	Example: `  eris chains clone simplechain risky -> will branch simplechain into risky
+  eris chains clone simplechain risky --id risky -> will also give risky its own chain_id`,
+	Run: func(cmd *cobra.Command, args []string) {
+		CloneChain(cmd, args)
+	},
+}
+
+var chainsGC = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove expired and orphaned throwaway chains.",
+	Long: `Remove expired and orphaned throwaway chains.
+
+Throwaway chains (and chains made with eris chains new --ttl) are
+marked ephemeral in their definition file. This command removes the
+containers, data containers, definition file, and host data directory
+of every ephemeral chain whose ttl has run out. It also removes
+throwaway chains which lost their definition file or all of their
+containers. Only chains eris made as throwaway are ever removed, and
+the data of a service with the same name is kept.
+
+The chains are listed and removed once you confirm; --force skips
+the question. Set GCOnStartup = true in eris.toml to remove them,
+without asking, before every command.`,
+	Example: `  eris chains gc --dry-run -> will list what would be removed
+  eris chains gc -f -> will remove them without asking`,
+	Run: func(cmd *cobra.Command, args []string) {
+		GCChains(cmd, args)
+	},
+}
+
+var chainsPool = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage the pool of pre-made throwaway chains.",
+	Long: `Manage the pool of pre-made throwaway chains.
+
+When the pool has an idle chain, eris contracts test (without --chain)
+leases it instead of making a throwaway chain from scratch. After the
+test the chain is removed, or, when ChainPoolReset = true is set in
+eris.toml, reset to the snapshot taken when it was made and put back.
+Either way the pool is then topped up to ChainPoolSize by an
+[eris chains pool fill] running in the background. eris chains gc
+counts the ttl of a leased chain from when it was leased.`,
+	Run: func(cmd *cobra.Command, args []string) { cmd.Help() },
+}
+
+var chainsPoolFill = &cobra.Command{
+	Use:   "fill",
+	Short: "Make throwaway chains until the pool is full.",
+	Long:  `Make and start throwaway chains until --size of them are idle in the pool.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		FillChainPool(cmd, args)
+	},
+}
+
+var chainsPoolList = &cobra.Command{
+	Use:   "ls",
+	Short: "List the idle chains in the pool.",
+	Long:  `List the idle chains in the pool.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ListChainPool(cmd, args)
+	},
+}
+
+var chainsPoolDrain = &cobra.Command{
+	Use:   "drain",
+	Short: "Remove the idle chains in the pool.",
+	Long:  `Remove the idle chains in the pool. Leased chains are not touched.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		DrainChainPool(cmd, args)
+	},
+}
+
 var chainsExport = &cobra.Command{
 	Use:   "export [chainName]",
 	Short: "Export a chain definition file to IPFS.",
@@ -228,7 +420,11 @@ Command will return a machine readable version of the IPFS hash
 var chainsRename = &cobra.Command{
 	Use:   "rename [old] [new]",
 	Short: "Rename a blockchain.",
-	Long:  `Rename a blockchain.`,
+	Long: `Rename a blockchain.
+
+All of the chain's containers, of every container number, its data
+containers and its definition file are renamed. If any step fails
+the steps already done are undone. Use --dry-run to see the steps.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		RenameChain(cmd, args)
 	},
@@ -268,10 +464,38 @@ by the update command.`,
 	},
 }
 
+var chainsUpgrade = &cobra.Command{
+	Use:   "upgrade [name]",
+	Short: "Move a chain to another version of its node.",
+	Long: `Move a chain to another version of its node.
+
+Chains are pinned to the node version they were made with by the
+erisdb_version field of their definition file. Upgrading the eris
+cli does not change it. This command will:
+
+1. Stop the chain (if it is running)
+2. Snapshot the chain's data (see [eris data snapshots])
+3. Pull the image for the new version
+4. Pin the chain to the new version and recreate its container
+5. Wait for the new node to reach the block height the chain was at
+
+If the last step fails the data is rolled back to the snapshot, the
+erisdb_version is put back, and the container is recreated from the
+old image. The snapshot is kept, tagged upgrade-<time>.`,
+	Example: `  eris chains upgrade simplechain --to 0.11 -> will run simplechain on eris/erisdb:0.11
+  eris chains upgrade simplechain --to sha256:<digest> -> will pin simplechain to an exact image`,
+	Run: func(cmd *cobra.Command, args []string) {
+		UpgradeChain(cmd, args)
+	},
+}
+
 var chainsGraduate = &cobra.Command{
-	Use:   "graduate",
+	Use:   "graduate [name]",
 	Short: "Graduates a chain to a service.",
-	Long:  `Graduates a chain to a service by laying a service definition file with the chain_id`,
+	Long: `Graduates a chain to a service by laying a service definition file with the chain_id.
+
+The service keeps the chain's data container, ports, environment,
+type and metadata. Turn it back into a chain with [eris services demote].`,
 	Run: func(cmd *cobra.Command, args []string) {
 		GraduateChain(cmd, args)
 	},
@@ -288,6 +512,12 @@ Command will cat local chains definition file.`,
 	},
 }
 
+func buildChainsPoolCommand() {
+	chainsPool.AddCommand(chainsPoolFill)
+	chainsPool.AddCommand(chainsPoolList)
+	chainsPool.AddCommand(chainsPoolDrain)
+}
+
 //----------------------------------------------------------------------
 
 func addChainsFlags() {
@@ -295,10 +525,15 @@ func addChainsFlags() {
 	chainsNew.PersistentFlags().StringVarP(&do.ConfigFile, "config", "c", "", "main config file for the chain")
 	chainsNew.PersistentFlags().StringVarP(&do.Path, "dir", "", "", "a directory whose contents should be copied into the chain's main dir")
 	chainsNew.PersistentFlags().BoolVarP(&do.Run, "run", "r", false, "run the chain after creating")
+	chainsNew.PersistentFlags().StringVarP(&do.Key, "key", "", "", "name or address of a key in the keys service for the chain's validator")
+	chainsNew.PersistentFlags().BoolVarP(&do.Test, "test", "", false, "smoke test the chain after running it (needs --run)")
+	chainsNew.PersistentFlags().StringVarP(&do.Chain.TTL, "ttl", "", "", "make an ephemeral chain which eris chains gc removes after this long (e.g. 2h)")
+	chainsNew.PersistentFlags().StringVarP(&do.ChainType, "type", "", "mint", "type of chain to make (built in or defined in ~/.eris/blockchains/types)")
 
 	chainsInstall.PersistentFlags().StringVarP(&do.ConfigFile, "config", "c", "", "main config file for the chain")
 	chainsInstall.PersistentFlags().StringVarP(&do.Path, "dir", "", "", "a directory whose contents should be copied into the chain's main dir")
 	chainsInstall.PersistentFlags().StringVarP(&do.ChainID, "id", "", "", "id of the chain to fetch")
+	chainsInstall.PersistentFlags().StringVarP(&do.ChainType, "type", "", "mint", "type of chain to fetch")
 	chainsInstall.PersistentFlags().BoolVarP(&do.Operations.PublishAllPorts, "publish", "p", false, "publish all ports")
 
 	chainsStart.PersistentFlags().BoolVarP(&do.Operations.PublishAllPorts, "publish", "p", false, "publish all ports")
@@ -308,16 +543,46 @@ func addChainsFlags() {
 	chainsLogs.Flags().StringVarP(&do.Tail, "tail", "t", "all", "number of lines to show from end of logs")
 
 	chainsRemove.Flags().BoolVarP(&do.File, "file", "f", false, "remove chain definition file as well as chain container")
-	chainsRemove.Flags().BoolVarP(&do.RmD, "data", "x", false, "remove data containers also")
+	chainsRemove.Flags().BoolVarP(&do.RmD, "data", "x", false, "remove data containers or named data volumes also")
 
 	chainsUpdate.Flags().BoolVarP(&do.SkipPull, "pull", "p", true, "pull an updated version of the chain's base service image from docker hub")
 	chainsUpdate.Flags().UintVarP(&do.Timeout, "timeout", "t", 10, "manually set the timeout; overridden by --force")
 
+	chainsUpgrade.Flags().StringVarP(&do.Version, "to", "", "", "version (image tag) or sha256:<digest> to upgrade to")
+	chainsUpgrade.Flags().UintVarP(&do.Timeout, "timeout", "t", 60, "seconds the new node has to pass its health check")
+	chainsUpgrade.Flags().BoolVarP(&do.Operations.PublishAllPorts, "publish", "p", false, "publish all ports")
+
 	chainsStop.Flags().BoolVarP(&do.Rm, "rm", "r", false, "remove containers after stopping")
 	chainsStop.Flags().BoolVarP(&do.RmD, "data", "x", false, "remove data containers after stopping")
 	chainsStop.Flags().BoolVarP(&do.Force, "force", "f", false, "kill the container instantly without waiting to exit")
 	chainsStop.Flags().UintVarP(&do.Timeout, "timeout", "t", 10, "manually set the timeout; overridden by --force")
 
+	chainsConfig.Flags().BoolVarP(&do.Force, "force", "f", false, "restart a running chain without asking")
+
+	chainsJoin.Flags().StringVarP(&do.GenesisFile, "genesis", "g", "", "genesis file or IPFS hash of the network")
+	chainsJoin.Flags().StringSliceVarP(&do.Seeds, "seeds", "s", []string{}, "comma separated list of host:port seeds to peer with")
+	chainsJoin.Flags().StringVarP(&do.Moniker, "moniker", "m", "", "name of the node on the network (defaults to the hostname)")
+	chainsJoin.Flags().StringVarP(&do.ChainType, "type", "", "mint", "type of chain to join")
+	chainsJoin.Flags().UintVarP(&do.Timeout, "timeout", "t", 0, "seconds to report sync progress for; 0 follows until synced")
+	chainsJoin.Flags().BoolVarP(&do.Operations.PublishAllPorts, "publish", "p", false, "publish all ports")
+
+	chainsStatus.Flags().BoolVarP(&do.Watch, "watch", "w", false, "keep refreshing the status until interrupted")
+	chainsStatus.Flags().StringVarP(&do.ResultFormt, "format", "", "", "output format; json for machine readable output")
+
+	chainsTest.Flags().StringVarP(&do.Path, "junit", "j", "", "write the results as JUnit XML to this file")
+
+	chainsWait.Flags().IntVarP(&do.Height, "height", "", 1, "block height the chain has to reach")
+	chainsWait.Flags().UintVarP(&do.Timeout, "timeout", "t", 60, "seconds to wait before giving up")
+
+	chainsPoolFill.Flags().IntVarP(&do.Size, "size", "s", 0, "number of idle chains to keep in the pool (defaults to ChainPoolSize in eris.toml)")
+
+	chainsClone.Flags().StringVarP(&do.ChainID, "id", "", "", "chain_id of the new chain (defaults to the source's)")
+
+	chainsRename.Flags().BoolVarP(&do.DryRun, "dry-run", "", false, "only list the changes which would be made")
+
+	chainsGC.Flags().BoolVarP(&do.DryRun, "dry-run", "", false, "only list the chains which would be removed")
+	chainsGC.Flags().BoolVarP(&do.Force, "force", "f", false, "remove without asking")
+
 	chainsList.Flags().BoolVarP(&do.Quiet, "quiet", "q", false, "machine parsable output")
 
 	chainsListRunning.Flags().BoolVarP(&do.Quiet, "quiet", "q", false, "machine parsable output")
@@ -355,6 +620,19 @@ func InstallChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.InstallChain(do))
 }
 
+func ConfigChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "ge", cmd, args))
+	do.Name = args[0]
+	do.Args = args[1:]
+	IfExit(chns.ChainConfig(do))
+}
+
+func JoinChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.JoinChain(do))
+}
+
 // create a new chain
 //
 // genesis is either given or a simple single-validator genesis will be laid for you
@@ -397,6 +675,53 @@ func InspectChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.InspectChain(do))
 }
 
+func StatusChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.StatusChain(do))
+}
+
+func WaitChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.WaitChain(do))
+}
+
+func SmokeTestChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	if len(args) > 1 {
+		do.ConfigFile = args[1]
+	}
+	IfExit(chns.SmokeTestChain(do))
+}
+
+func CloneChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name = args[0]
+	do.NewName = args[1]
+	IfExit(chns.CloneChain(do))
+}
+
+func GCChains(cmd *cobra.Command, args []string) {
+	IfExit(chns.GCChains(do))
+}
+
+func FillChainPool(cmd *cobra.Command, args []string) {
+	if do.Size == 0 {
+		do.Size = util.GlobalConfig.Config.ChainPoolSize
+	}
+	IfExit(chns.FillChainPool(do))
+}
+
+func ListChainPool(cmd *cobra.Command, args []string) {
+	IfExit(chns.ListChainPool(do))
+}
+
+func DrainChainPool(cmd *cobra.Command, args []string) {
+	IfExit(chns.DrainChainPool(do))
+}
+
 func ExportChain(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
@@ -436,6 +761,12 @@ func UpdateChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.UpdateChain(do))
 }
 
+func UpgradeChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.UpgradeChain(do))
+}
+
 func RmChain(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/eris-ltd/eris-cli/chains"
 	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/services"
+	"github.com/eris-ltd/eris-cli/util"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
@@ -45,13 +48,30 @@ func buildDataCommand() {
 	Data.AddCommand(dataExport)
 	Data.AddCommand(dataExec)
 	Data.AddCommand(dataRm)
+	Data.AddCommand(dataSync)
+	Data.AddCommand(dataDiff)
+	Data.AddCommand(dataDu)
+	Data.AddCommand(dataPrune)
+	Data.AddCommand(dataMigrate)
+	Data.AddCommand(dataSnapshot)
+	Data.AddCommand(dataSnapshots)
+	Data.AddCommand(dataRollback)
 	addDataFlags()
 }
 
 var dataImport = &cobra.Command{
-	Use:   "import [name]",
+	Use:   "import [name] [source]",
 	Short: "Import ~/.eris/data/name folder to a named data container",
-	Long:  `Import ~/.eris/data/name folder to a named data container`,
+	Long: `Import ~/.eris/data/name folder to a named data container.
+
+Give a source to import a file or directory from IPFS (ipfs:<hash>) or
+a URL instead. IPFS files are checked against their hashes. A URL must
+carry the sha256 of its content in its fragment
+(https://host/file#sha256=<hex>) and is checked against it; with
+--skip-verify a URL without one is imported and its sha256 printed.`,
+	Example: `  eris data import 2gather -> will import ~/.eris/data/2gather into the 2gather data container
+  eris data import 2gather ipfs:QmXUX8... -> will import the QmXUX8... directory from IPFS
+  eris data import 2gather https://example.com/genesis.json#sha256=5d8a... -> will import genesis.json`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ImportData(cmd, args)
 	},
@@ -129,6 +149,131 @@ var dataRm = &cobra.Command{
 	},
 }
 
+var dataSync = &cobra.Command{
+	Use:   "sync [name]",
+	Short: "Bring a data container up to date with ~/.eris/data/name",
+	Long: `Bring a data container up to date with ~/.eris/data/name.
+
+Only files which are new or differ from the ones in the container are
+copied in. With --watch eris keeps watching the folder and copies every
+file created or changed into the container as it happens, and removes
+every file deleted from the folder, until interrupted.
+
+Files matching an --ignore pattern are left alone, as are .git, editor
+swap and backup files.`,
+	Example: `  eris data sync 2gather --watch -> will keep the 2gather data container in step with ~/.eris/data/2gather
+  eris data sync 2gather --watch --ignore "*.log" --ignore tmp -> will leave logs and tmp alone`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SyncData(cmd, args)
+	},
+}
+
+var dataDiff = &cobra.Command{
+	Use:   "diff [name]",
+	Short: "Show how a data container differs from ~/.eris/data/name",
+	Long: `Show how a data container differs from ~/.eris/data/name.
+
+Files are compared by size and hash and listed as added (A) when only
+the host folder has them, deleted (D) when only the container has
+them, and modified (M) when they differ. With --content the changes
+to text files are shown as well, the container's copy first.`,
+	Example: `  eris data diff 2gather -> will list what differs between ~/.eris/data/2gather and the 2gather data container
+  eris data diff 2gather --content -> will show the changed lines as well`,
+	Run: func(cmd *cobra.Command, args []string) {
+		DiffData(cmd, args)
+	},
+}
+
+var dataDu = &cobra.Command{
+	Use:   "du",
+	Short: "Show how much space each data container takes up",
+	Long: `Show how much space the volumes of each data container, and the
+named data volumes of each service or chain, take up, largest first,
+along with the service or chain they belong to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		DiskUsage(cmd, args)
+	},
+}
+
+var dataPrune = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove data containers and folders nothing uses",
+	Long: `Remove data containers and folders nothing uses.
+
+A data container is removed, with its volumes, when there is neither a
+service or chain definition nor a service or chain container of the
+same name; so are named data volumes. A folder in ~/.eris/data is
+removed when there is no data container, named data volume, or service
+or chain definition of the same name. eris lists what it will remove
+and asks before removing anything, unless --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		PruneData(cmd, args)
+	},
+}
+
+var dataMigrate = &cobra.Command{
+	Use:   "migrate [name]",
+	Short: "Move a service's or chain's data into named volumes",
+	Long: `Move the data of a service or chain out of its data container into
+named Docker volumes.
+
+The data is copied into a volume for each volume of the data container
+and checked against it, the service or chain container is recreated
+with the volumes mounted at the same paths in place of the data
+container, and the data container is removed. The definition gets
+data_volumes = true and the paths as data_paths in its [service]
+section so the service or chain keeps using the volumes. It is stopped
+for the move and started again if it was running.
+
+Data containers and named volumes can be mixed freely; the eris data
+commands work with either, whether or not the service or chain has a
+container. [eris services rm -x] and [eris chains rm -x] remove the
+volumes.`,
+	Example: `  eris data migrate ipfs -> will move the ipfs service's data into the eris_data_ipfs_1 volume`,
+	Run: func(cmd *cobra.Command, args []string) {
+		MigrateData(cmd, args)
+	},
+}
+
+var dataSnapshot = &cobra.Command{
+	Use:   "snapshot [name]",
+	Short: "Save a snapshot of a data container's volumes",
+	Long: `Save a snapshot of a data container's volumes.
+
+Snapshots are kept in ~/.eris/snapshots. Files which have not changed
+since an earlier snapshot are only stored once. Works for the data
+container of any service with data_container = true as well as chains.
+
+The snapshot is tagged with the current time unless --tag is given.`,
+	Example: `  eris data snapshot ipfs --tag clean -> will snapshot the ipfs data container as clean`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SnapshotData(cmd, args)
+	},
+}
+
+var dataSnapshots = &cobra.Command{
+	Use:   "snapshots [name]",
+	Short: "List the snapshots of a data container",
+	Long:  `List the snapshots of a data container, oldest first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ListSnapshots(cmd, args)
+	},
+}
+
+var dataRollback = &cobra.Command{
+	Use:   "rollback [name] [tag]",
+	Short: "Put a data container back the way it was in a snapshot",
+	Long: `Put a data container back the way it was in a snapshot.
+
+The volumes are restored in place: files changed since the snapshot
+are put back and files added since are removed. Stop the service or
+chain using the data container first, or use --force.`,
+	Example: `  eris data rollback ipfs clean -> will reset the ipfs data container to the clean snapshot`,
+	Run: func(cmd *cobra.Command, args []string) {
+		RollbackData(cmd, args)
+	},
+}
+
 //----------------------------------------------------
 
 func addDataFlags() {
@@ -136,7 +281,23 @@ func addDataFlags() {
 	dataExec.Flags().BoolVarP(&do.Interactive, "interactive", "i", false, "interactive shell")
 
 	dataImport.Flags().StringVarP(&do.Path, "dest", "", "", "destination for import into data container")
+	dataImport.Flags().BoolVarP(&do.SkipVerify, "skip-verify", "", false, "import a URL which carries no #sha256= to check it against")
 	dataExport.Flags().StringVarP(&do.Path, "src", "", "", "source inside data container to export from")
+
+	dataSync.Flags().StringVarP(&do.Path, "dest", "", "", "destination for the files inside the data container")
+	dataSync.Flags().BoolVarP(&do.Watch, "watch", "w", false, "keep copying changes in until interrupted")
+	dataSync.Flags().StringSliceVarP(&do.Ignore, "ignore", "i", []string{}, "pattern of files to leave alone; can be repeated")
+
+	dataDiff.Flags().StringVarP(&do.Path, "path", "", "", "folder inside the data container to compare")
+	dataDiff.Flags().BoolVarP(&do.Content, "content", "c", false, "show the changes to text files")
+
+	dataPrune.Flags().BoolVarP(&do.Force, "force", "f", false, "remove without asking")
+
+	dataMigrate.Flags().UintVarP(&do.Timeout, "timeout", "t", 10, "seconds to wait for the service or chain to stop")
+
+	dataSnapshot.Flags().StringVarP(&do.Tag, "tag", "t", "", "tag for the snapshot; defaults to the current time")
+	dataSnapshot.Flags().BoolVarP(&do.Force, "force", "f", false, "replace a snapshot with the same tag")
+	dataRollback.Flags().BoolVarP(&do.Force, "force", "f", false, "roll back even if the service or chain is running")
 }
 
 //----------------------------------------------------
@@ -180,6 +341,9 @@ func RmData(cmd *cobra.Command, args []string) {
 func ImportData(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
+	if len(args) > 1 {
+		do.Source = args[1]
+	}
 	setDefaultDir()
 	IfExit(data.ImportData(do))
 }
@@ -191,6 +355,57 @@ func ExportData(cmd *cobra.Command, args []string) {
 	IfExit(data.ExportData(do))
 }
 
+func SyncData(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	setDefaultDir()
+	IfExit(data.SyncData(do))
+}
+
+func DiffData(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	setDefaultDir()
+	IfExit(data.DiffData(do))
+}
+
+func DiskUsage(cmd *cobra.Command, args []string) {
+	IfExit(data.DiskUsage(do))
+}
+
+func PruneData(cmd *cobra.Command, args []string) {
+	IfExit(data.PruneData(do))
+}
+
+func MigrateData(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	if util.GetFileByNameAndType("chains", do.Name) != "" {
+		IfExit(chains.MigrateChain(do))
+		return
+	}
+	IfExit(services.MigrateService(do))
+}
+
+func SnapshotData(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(data.SnapshotData(do))
+}
+
+func ListSnapshots(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(data.ListSnapshots(do))
+}
+
+func RollbackData(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name = args[0]
+	do.Tag = args[1]
+	IfExit(data.RollbackData(do))
+}
+
 func ExecData(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 
+	chns "github.com/eris-ltd/eris-cli/chains"
 	"github.com/eris-ltd/eris-cli/definitions"
 	"github.com/eris-ltd/eris-cli/util"
 	"github.com/eris-ltd/eris-cli/version"
@@ -40,6 +41,16 @@ Complete documentation is available at https://docs.erisindustries.com
 
 		common.InitErisDir()
 		util.DockerConnect(do.Verbose, do.MachineName)
+
+		// nobody is there to answer gc's question in scripts and CI;
+		// eris chains gc itself asks as usual
+		if util.GlobalConfig.Config.GCOnStartup && cmd != chainsGC {
+			gcDo := definitions.NowDo()
+			gcDo.Force = true
+			if err := chns.GCChains(gcDo); err != nil {
+				logger.Errorln(err)
+			}
+		}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		err := util.SaveGlobalConfig(util.GlobalConfig.Config)
@@ -73,6 +84,8 @@ func AddCommands() {
 	ErisCmd.AddCommand(Data)
 	buildFilesCommand()
 	ErisCmd.AddCommand(Files)
+	buildKeysCommand()
+	ErisCmd.AddCommand(Keys)
 	// buildRemotesCommand()
 	// ErisCmd.AddCommand(Remotes)
 	buildConfigCommand()
@@ -83,6 +96,7 @@ func AddCommands() {
 	ErisCmd.AddCommand(Config)
 	ErisCmd.AddCommand(VerSion)
 	ErisCmd.AddCommand(Init)
+	ErisCmd.AddCommand(Recover)
 }
 
 // Global Do struct
@@ -96,6 +110,8 @@ func AddGlobalFlags() {
 	ErisCmd.PersistentFlags().IntVarP(&do.Operations.ContainerNumber, "num", "n", 1, "container number")
 	ErisCmd.PersistentFlags().StringVarP(&do.MachineName, "machine", "", "eris", "machine name for docker-machine that is running VM")
 	Init.Flags().BoolVarP(&do.SkipPull, "skip-pull", "p", false, "skip the pulling feature; for when git is not installed")
+	Recover.Flags().BoolVarP(&do.Force, "force", "f", false, "also undo journals of eris commands which look to be still running")
+	Recover.Flags().BoolVarP(&do.DryRun, "dry-run", "", false, "only show what would be undone")
 	// Init.Flags().BoolVarP(&do.Dev, "dev", "", false, "pull development images")
 	// Init.Flags().BoolVarP(&do.SkipImages, "no-pull", "", false, "skip pulling default images")
 }
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+// every command's flags merge with the global ones without clashing;
+// cobra panics on a clash as soon as the command is run, even with --help
+func TestCommandFlags(t *testing.T) {
+	do = definitions.NowDo()
+	AddGlobalFlags()
+	AddCommands()
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if err := parseHelp(cmd); err != nil {
+			t.Errorf("%s: %v", cmd.CommandPath(), err)
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(ErisCmd)
+}
+
+func parseHelp(cmd *cobra.Command) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return cmd.ParseFlags([]string{"--help"})
+}
@@ -25,13 +25,22 @@ func buildFilesCommand() {
 	Files.AddCommand(filesCat)
 	Files.AddCommand(filesList)
 	Files.AddCommand(filesCached)
-	//	addFilesFlags()
+	addFilesFlags()
 }
 
 var filesImport = &cobra.Command{
 	Use:   "get [hash] [fileName]",
 	Short: "Pull a file from IPFS via its hash and save it locally.",
-	Long:  `Pull a file from IPFS via its hash and save it locally.`,
+	Long: `Pull a file from IPFS via its hash and save it locally.
+
+Files are checked against their hashes before they are saved. Failed
+downloads are tried again (DownloadRetries and DownloadBackoff in
+eris.toml) and resumed from where they stopped.
+
+With --recursive the hash is a folder and the whole tree under
+it is saved into [fileName], which will be created if need be.`,
+	Example: `$ eris files get QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG readme.md
+$ eris files get -r QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn dapp`,
 	Run: func(cmd *cobra.Command, args []string) {
 		Get(cmd, args)
 	},
@@ -40,7 +49,12 @@ var filesImport = &cobra.Command{
 var filesExport = &cobra.Command{
 	Use:   "put [fileName]",
 	Short: "Post a file to IPFS.",
-	Long:  `Post a file to IPFS.`,
+	Long: `Post a file to IPFS.
+
+With --recursive [fileName] is a folder. Everything under it is
+added as one IPFS directory and the folder's hash is returned.`,
+	Example: `$ eris files put readme.md
+$ eris files put -r dapp`,
 	Run: func(cmd *cobra.Command, args []string) {
 		Put(cmd, args)
 	},
@@ -88,6 +102,14 @@ var filesCached = &cobra.Command{
 //--------------------------------------------------------------
 // cli flags
 
+func addFilesFlags() {
+	filesImport.Flags().BoolVarP(&do.Recursive, "recursive", "r", false, "get a whole folder")
+	filesExport.Flags().BoolVarP(&do.Recursive, "recursive", "r", false, "put a whole folder")
+}
+
+//--------------------------------------------------------------
+// cli command wrappers
+
 func Get(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(2, "eq", cmd, args))
 
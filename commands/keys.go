@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"github.com/eris-ltd/eris-cli/keys"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+//----------------------------------------------------
+
+// Primary Keys Sub-Command
+var Keys = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys held by the keys service.",
+	Long: `Manage the keys held by the keys service.
+
+The keys service is started when needed. All commands talk to its
+HTTP API; no need to exec into the keys container.`,
+	Run: func(cmd *cobra.Command, args []string) { cmd.Help() },
+}
+
+// build the keys subcommand
+func buildKeysCommand() {
+	Keys.AddCommand(keysGen)
+	Keys.AddCommand(keysList)
+	Keys.AddCommand(keysShow)
+	Keys.AddCommand(keysImport)
+	Keys.AddCommand(keysExport)
+	Keys.AddCommand(keysConvert)
+	Keys.AddCommand(keysBackup)
+	Keys.AddCommand(keysRestore)
+	addKeysFlags()
+}
+
+var keysGen = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate a new key.",
+	Long:  `Generate a new key and print its address.`,
+	Example: `  eris keys gen -> will make an unnamed ed25519 key
+  eris keys gen --name validator -> will make a key named validator`,
+	Run: func(cmd *cobra.Command, args []string) {
+		GenKey(cmd, args)
+	},
+}
+
+var keysList = &cobra.Command{
+	Use:   "ls",
+	Short: "List the named keys.",
+	Long:  `List the named keys and their addresses.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ListKeys(cmd, args)
+	},
+}
+
+var keysShow = &cobra.Command{
+	Use:   "show [name|address]",
+	Short: "Show the address and public key of a key.",
+	Long:  `Show the address and public key of a key.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ShowKey(cmd, args)
+	},
+}
+
+var keysImport = &cobra.Command{
+	Use:   "import [file|hex]",
+	Short: "Import a key file or hex encoded private key.",
+	Long:  `Import a key file or hex encoded private key into the keys service.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ImportKey(cmd, args)
+	},
+}
+
+var keysExport = &cobra.Command{
+	Use:   "export [name|address]",
+	Short: "Copy a key out of the keys service.",
+	Long:  `Copy a key file out of the keys service into ~/.eris/keys/data.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ExportKey(cmd, args)
+	},
+}
+
+var keysConvert = &cobra.Command{
+	Use:   "convert [name|address]",
+	Short: "Print a key as a priv_validator.json.",
+	Long: `Print a key as a priv_validator.json.
+
+Only unencrypted ed25519 keys can be converted.`,
+	Example: `  eris keys convert validator > priv_validator.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ConvertKey(cmd, args)
+	},
+}
+
+var keysBackup = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up all keys to an encrypted file.",
+	Long: `Back up every key and key name in the keys data container
+to a file encrypted with a passphrase (AES-256-GCM).
+
+The passphrase is read from $ERIS_KEYS_PASSPHRASE or asked for.`,
+	Example: `  eris keys backup --out keys.backup`,
+	Run: func(cmd *cobra.Command, args []string) {
+		BackupKeys(cmd, args)
+	},
+}
+
+var keysRestore = &cobra.Command{
+	Use:   "restore [file]",
+	Short: "Restore keys from an encrypted backup.",
+	Long: `Restore keys from a backup made by [eris keys backup].
+
+Keys which already exist with different contents are not
+overwritten unless --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		RestoreKeys(cmd, args)
+	},
+}
+
+func addKeysFlags() {
+	keysGen.Flags().StringVarP(&do.Name, "name", "", "", "name of the new key")
+	keysGen.Flags().StringVarP(&do.Type, "type", "t", keys.DefaultKeyType, "type of the new key")
+
+	keysImport.Flags().StringVarP(&do.Name, "name", "", "", "name of the imported key")
+	keysImport.Flags().StringVarP(&do.Type, "type", "t", keys.DefaultKeyType, "type of the imported key")
+
+	keysBackup.Flags().StringVarP(&do.Path, "out", "o", "", "file to write the backup to")
+
+	keysRestore.Flags().BoolVarP(&do.Force, "force", "f", false, "overwrite existing keys")
+}
+
+//----------------------------------------------------
+
+func GenKey(cmd *cobra.Command, args []string) {
+	IfExit(keys.GenKey(do))
+}
+
+func ListKeys(cmd *cobra.Command, args []string) {
+	IfExit(keys.ListKeys(do))
+}
+
+func ShowKey(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(keys.ShowKey(do))
+}
+
+func ImportKey(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Path = args[0]
+	IfExit(keys.ImportKey(do))
+}
+
+func ExportKey(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(keys.ExportKey(do))
+}
+
+func ConvertKey(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(keys.ConvertKey(do))
+}
+
+func BackupKeys(cmd *cobra.Command, args []string) {
+	IfExit(keys.BackupKeys(do))
+}
+
+func RestoreKeys(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Path = args[0]
+	IfExit(keys.RestoreKeys(do))
+}
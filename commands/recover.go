@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"github.com/eris-ltd/eris-cli/perform"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+var Recover = &cobra.Command{
+	Use:   "recover",
+	Short: "Finish cleaning up after an eris command which crashed.",
+	Long: `Undo what was left half done by eris commands which did not finish.
+
+Commands which touch several containers and files (starting services,
+setting up and renaming chains, rebuilding containers, running contracts)
+keep a journal of what they did in ~/.eris/journal. When a command fails
+it undoes its steps itself; when it crashes, the journal stays behind and
+[eris recover] undoes the steps for it.
+
+Journals of eris commands which are still running are skipped unless
+--force is given.`,
+	Example: `$ eris recover --dry-run -- show what would be undone
+$ eris recover`,
+	Run: func(cmd *cobra.Command, args []string) {
+		IfExit(perform.Recover(do.Force, do.DryRun))
+	},
+}
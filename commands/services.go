@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 
+	chns "github.com/eris-ltd/eris-cli/chains"
 	srv "github.com/eris-ltd/eris-cli/services"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
@@ -40,6 +41,7 @@ func buildServicesCommand() {
 	Services.AddCommand(servicesUpdate)
 	Services.AddCommand(servicesRm)
 	Services.AddCommand(servicesCat)
+	Services.AddCommand(servicesDemote)
 	addServicesFlags()
 }
 
@@ -130,6 +132,9 @@ eris stores in the ~/.eris/services directory.
 [eris services start name] by default will put the service into the
 background so its logs will not be viewable from the command line.
 
+Chains the service relies upon are started first, and the service is
+only started once they are producing blocks.
+
 To stop the service use:      [eris services stop serviceName].
 To view a service's logs use: [eris services logs serviceName].`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -196,7 +201,11 @@ var servicesStop = &cobra.Command{
 var servicesRename = &cobra.Command{
 	Use:   "rename [oldName] [newName]",
 	Short: "Renames an installed service.",
-	Long:  `Renames an installed service.`,
+	Long: `Renames an installed service.
+
+All of the service's containers, of every container number, its data
+containers and its definition file are renamed. If any step fails
+the steps already done are undone. Use --dry-run to see the steps.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		RenameService(cmd, args)
 	},
@@ -248,6 +257,22 @@ Command will cat local service definition file.`,
 	},
 }
 
+var servicesDemote = &cobra.Command{
+	Use:   "demote [name]",
+	Short: "Turns a graduated service back into a chain.",
+	Long: `Turns a service back into a chain which the chains
+commands manage. This is the inverse of [eris chains graduate].
+
+The chain keeps the service's data container, ports, environment,
+maintainer and location. The service definition file is removed.
+The service has to be stopped first.`,
+	Example: `  eris chains graduate simplechain -> will make the service simplechain_id
+  eris services demote simplechain_id -> will make it the chain simplechain again`,
+	Run: func(cmd *cobra.Command, args []string) {
+		DemoteService(cmd, args)
+	},
+}
+
 //----------------------------------------------------------------------
 // cli flags
 
@@ -268,7 +293,11 @@ func addServicesFlags() {
 	servicesStop.Flags().UintVarP(&do.Timeout, "timeout", "t", 10, "manually set the timeout; overridden by --force")
 
 	servicesRm.Flags().BoolVarP(&do.File, "file", "f", false, "remove service definition file as well as service container")
-	servicesRm.Flags().BoolVarP(&do.RmD, "data", "x", false, "remove data containers as well")
+	servicesRm.Flags().BoolVarP(&do.RmD, "data", "x", false, "remove data containers or named data volumes as well")
+
+	servicesRename.Flags().BoolVarP(&do.DryRun, "dry-run", "", false, "only list the changes which would be made")
+
+	servicesDemote.Flags().BoolVarP(&do.Force, "force", "f", false, "overwrite an existing chain definition")
 
 	servicesListExisting.Flags().BoolVarP(&do.Quiet, "quiet", "q", false, "machine parsable output")
 	servicesListRunning.Flags().BoolVarP(&do.Quiet, "quiet", "q", false, "machine parsable output")
@@ -381,3 +410,9 @@ func CatService(cmd *cobra.Command, args []string) {
 	do.Name = args[0]
 	IfExit(srv.CatService(do))
 }
+
+func DemoteService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.DemoteService(do))
+}
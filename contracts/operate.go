@@ -17,7 +17,7 @@ import (
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 )
 
-func RunPackage(do *definitions.Do) error {
+func RunPackage(do *definitions.Do) (err error) {
 	logger.Debugf("Welcome! Say the Marmots. Running DApp package.\n")
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -31,22 +31,29 @@ func RunPackage(do *definitions.Do) error {
 		return err
 	}
 
-	if err := BootServicesAndChain(do, dapp); err != nil {
+	// everything set up for the run is journaled so a failure undoes
+	// exactly what was done; [eris recover] finishes it after a crash
+	j := perform.NewJournal("Contracts", false)
+	defer func() {
+		if err != nil {
+			returnPoolChain(do)
+			err = j.Rollback(err)
+		}
+	}()
+
+	if err := BootServicesAndChain(do, dapp, j); err != nil {
 		do.Result = "could not boot chain or services"
-		CleanUp(do, dapp)
 		return err
 	}
 
 	do.Path = pwd
-	if err := DefineDappActionService(do, dapp); err != nil {
+	if err := DefineDappActionService(do, dapp, j); err != nil {
 		do.Result = "could not define dapp action service"
-		CleanUp(do, dapp)
 		return err
 	}
 
 	if err := PerformDappActionService(do, dapp); err != nil {
 		do.Result = "could not perform dapp action service"
-		CleanUp(do, dapp)
 		return err
 	}
 
@@ -54,12 +61,15 @@ func RunPackage(do *definitions.Do) error {
 		do.Result = "could not cleanup"
 		return err
 	}
+	if err := j.Commit(); err != nil {
+		return err
+	}
 
 	do.Result = "success"
 	return nil
 }
 
-func BootServicesAndChain(do *definitions.Do, dapp *definitions.Contracts) error {
+func BootServicesAndChain(do *definitions.Do, dapp *definitions.Contracts, j *perform.Journal) error {
 	var err error
 	var srvs []*definitions.ServiceDefinition
 
@@ -74,12 +84,15 @@ func BootServicesAndChain(do *definitions.Do, dapp *definitions.Contracts) error
 
 	// TODO: refactor this logic, should only need to call services.StartGroup(srvs)
 	if len(srvs) >= 1 {
-		wg, ch := new(sync.WaitGroup), make(chan error, 1)
+		for _, s := range srvs {
+			if err := services.RecordStart(j, s); err != nil {
+				return err
+			}
+		}
+		wg, ch := new(sync.WaitGroup), make(chan error, len(srvs))
 		services.StartGroup(ch, wg, srvs)
-		go func() {
-			wg.Wait()
-			ch <- nil
-		}()
+		wg.Wait()
+		close(ch)
 		if err := <-ch; err != nil {
 			return err
 		}
@@ -90,28 +103,38 @@ func BootServicesAndChain(do *definitions.Do, dapp *definitions.Contracts) error
 	case "":
 		if dapp.ChainName == "" {
 			logger.Infof("No chain was given, booting a throwaway chain.\n")
-			err = bootThrowAwayChain(dapp.Name, do)
+			err = bootThrowAwayChain(dapp.Name, do, j)
 		} else {
 			logger.Infof("Booting chain =>\t\t%s\n", dapp.ChainName)
-			err = bootChain(dapp.ChainName, do)
+			err = bootChain(dapp.ChainName, do, j)
 		}
 	case "t", "tmp", "temp":
 		logger.Infof("No chain was given, booting a throwaway chain.\n")
-		err = bootThrowAwayChain(dapp.Name, do)
+		err = bootThrowAwayChain(dapp.Name, do, j)
 	default:
 		logger.Infof("Booting chain =>\t\t%s\n", do.ChainName)
-		err = bootChain(do.ChainName, do)
+		err = bootChain(do.ChainName, do, j)
 	}
-
 	dapp.ChainName = do.Chain.Name
 	if err != nil {
 		return err
 	}
 
+	// services acting as chains have no rpc the marmots know how to query
+	if do.Chain.ChainType != "service" {
+		chain, err := loaders.LoadChainDefinition(do.Chain.Name, false, do.Operations.ContainerNumber)
+		if err != nil {
+			return err
+		}
+		if err := chains.WaitForChain(chain, 1, chains.DefaultWaitTimeout); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func DefineDappActionService(do *definitions.Do, dapp *definitions.Contracts) error {
+func DefineDappActionService(do *definitions.Do, dapp *definitions.Contracts, j *perform.Journal) error {
 	var cmd string
 
 	switch do.Name {
@@ -182,10 +205,28 @@ func DefineDappActionService(do *definitions.Do, dapp *definitions.Contracts) er
 	}
 
 	loca := path.Join(common.DataContainersPath, doData.Name)
+	dataName := util.DataContainersName(doData.Name, doData.Operations.ContainerNumber)
+	// only what is made here is undone, recorded oldest first so the
+	// action container goes before its data
+	undos := []*perform.Undo{}
+	if _, err := os.Stat(loca); os.IsNotExist(err) {
+		undos = append(undos, &perform.Undo{Desc: "copy the dapp to " + loca, Kind: perform.UndoRemovePath, Args: []string{loca}})
+	}
+	if !util.IsDataContainer(doData.Name, doData.Operations.ContainerNumber) {
+		undos = append(undos, &perform.Undo{Desc: "create " + dataName, Kind: perform.UndoRemoveContainer, Args: []string{dataName}})
+	}
+	if _, exists := perform.ContainerExists(do.Operations); !exists {
+		undos = append(undos, &perform.Undo{Desc: "create " + do.Operations.SrvContainerName, Kind: perform.UndoRemoveContainer, Args: []string{do.Operations.SrvContainerName}})
+	}
+	for _, undo := range undos {
+		if err := j.Record(undo.Desc, undo); err != nil {
+			return err
+		}
+	}
 	logger.Debugf("Creating Dapp Data Cont =>\t%s:%s\n", do.Path, loca)
 	common.Copy(do.Path, loca)
 	data.ImportData(doData)
-	do.Operations.DataContainerName = util.DataContainersName(doData.Name, doData.Operations.ContainerNumber)
+	do.Operations.DataContainerName = dataName
 
 	logger.Debugf("DApp Action Built.\n")
 
@@ -208,17 +249,14 @@ func CleanUp(do *definitions.Do, dapp *definitions.Contracts) error {
 	logger.Infof("Commensing CleanUp.\n")
 
 	if do.Chain.ChainType == "throwaway" {
-		logger.Debugf("Destroying Throwaway Chain =>\t%s\n", do.Chain.Name)
-		doRm := definitions.NowDo()
-		doRm.Operations = do.Operations
-		doRm.Name = do.Chain.Name
-		doRm.Rm = true
-		doRm.RmD = true
-		chains.KillChain(doRm)
-
-		logger.Debugf("Removing latent files/dirs =>\t%s:%s\n", path.Join(common.DataContainersPath, do.Chain.Name), path.Join(common.BlockchainsPath, do.Chain.Name+".toml"))
-		os.RemoveAll(path.Join(common.DataContainersPath, do.Chain.Name))
-		os.Remove(path.Join(common.BlockchainsPath, do.Chain.Name+".toml"))
+		if chains.IsPoolChain(do.Chain.Name) {
+			returnPoolChain(do)
+		} else {
+			logger.Debugf("Destroying Throwaway Chain =>\t%s\n", do.Chain.Name)
+			if err := chains.RemoveThrowAwayChain(do.Chain.Name, do.Operations); err != nil {
+				logger.Errorln(err)
+			}
+		}
 	} else {
 		logger.Debugf("No Throwaway Chain to destroy.\n")
 	}
@@ -231,7 +269,30 @@ func CleanUp(do *definitions.Do, dapp *definitions.Contracts) error {
 	return nil
 }
 
-func bootChain(name string, do *definitions.Do) error {
+// returnPoolChain hands a leased pool chain back to the pool. Unlike
+// the rest of a package run a lease is not journaled.
+func returnPoolChain(do *definitions.Do) {
+	if do.Chain.ChainType != "throwaway" || !chains.IsPoolChain(do.Chain.Name) {
+		return
+	}
+	logger.Debugf("Returning Pool Chain =>\t%s\n", do.Chain.Name)
+	if err := chains.ReturnPoolChain(do.Chain.Name, util.GlobalConfig.Config.ChainPoolReset, util.GlobalConfig.Config.ChainPoolSize); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+func bootChain(name string, do *definitions.Do, j *perform.Journal) error {
+	// a chain (or service) which was not running is stopped again
+	// should the package run fail
+	cNum := do.Operations.ContainerNumber
+	if !util.IsChainContainer(name, cNum, false) && !util.IsServiceContainer(name, cNum, false) {
+		for _, contName := range []string{util.ChainContainersName(name, cNum), util.ServiceContainersName(name, cNum)} {
+			if err := j.Record("start "+contName, &perform.Undo{Kind: perform.UndoStopContainer, Args: []string{contName}}); err != nil {
+				return err
+			}
+		}
+	}
+
 	do.Chain.ChainType = "service" // setting this for tear down purposes
 	startChain := definitions.NowDo()
 	startChain.Name = name
@@ -255,12 +316,20 @@ func bootChain(name string, do *definitions.Do) error {
 	return nil
 }
 
-func bootThrowAwayChain(name string, do *definitions.Do) error {
+func bootThrowAwayChain(name string, do *definitions.Do, j *perform.Journal) error {
 	do.Chain.ChainType = "throwaway"
 
+	leased, err := chains.LeasePoolChain()
+	if err != nil {
+		logger.Infof("Could not lease a pool chain =>\t%v\n", err)
+	} else if leased != "" {
+		do.Chain.Name = leased // setting this for tear down purposes
+		return nil
+	}
+
 	tmp := do.Name
 	do.Name = name
-	err := chains.ThrowAwayChain(do)
+	err = chains.ThrowAwayChain(do)
 	if err != nil {
 		do.Name = tmp
 		return err
@@ -269,6 +338,27 @@ func bootThrowAwayChain(name string, do *definitions.Do) error {
 	do.Chain.Name = do.Name // setting this for tear down purposes
 	logger.Debugf("ThrowAwayChain booted =>\t%s\n", do.Name)
 
+	// the same teardown as RemoveThrowAwayChain, oldest first. The name
+	// ThrowAwayChain picked was not used by anything before, so all of
+	// these were made by it
+	cNum := do.Operations.ContainerNumber
+	if cNum == 0 {
+		cNum = 1
+	}
+	undos := []*perform.Undo{
+		{Desc: "record " + do.Name + " as throwaway", Kind: perform.UndoRemovePath, Args: []string{path.Join(chains.ThrowAwayPath(), do.Name)}},
+		{Desc: "write the " + do.Name + " definition", Kind: perform.UndoRemovePath, Args: []string{path.Join(common.BlockchainsPath, do.Name+".toml")}},
+		{Desc: "make " + path.Join(common.DataContainersPath, do.Name), Kind: perform.UndoRemovePath, Args: []string{path.Join(common.DataContainersPath, do.Name)}},
+		{Desc: "create " + util.DataContainersName(do.Name, cNum), Kind: perform.UndoRemoveContainer, Args: []string{util.DataContainersName(do.Name, cNum)}},
+		{Desc: "start " + util.ChainContainersName(do.Name, cNum), Kind: perform.UndoRemoveContainer, Args: []string{util.ChainContainersName(do.Name, cNum)}},
+	}
+	for _, undo := range undos {
+		if err := j.Record(undo.Desc, undo); err != nil {
+			do.Name = tmp
+			return err
+		}
+	}
+
 	do.Name = tmp
 	return nil
 }
@@ -1,14 +1,22 @@
 package data
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
 	"github.com/eris-ltd/eris-cli/definitions"
 	ini "github.com/eris-ltd/eris-cli/initialize"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/util"
 
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
@@ -204,6 +212,186 @@ func testsTearDown() error {
 	return nil
 }
 
+func TestSnapshotDedup(t *testing.T) {
+	root := common.ErisRoot
+	dir, err := ioutil.TempDir("", "eris_snapshots_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	common.ErisRoot = dir
+	defer func() {
+		common.ErisRoot = root
+		os.RemoveAll(dir)
+	}()
+
+	// the volume as docker copies it out, inside its own directory
+	volume := func(files map[string]string) *tar.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: ".eris/", Typeflag: tar.TypeDir, Mode: 0755})
+		tw.WriteHeader(&tar.Header{Name: ".eris/keys/", Typeflag: tar.TypeDir, Mode: 0700, Uname: "eris"})
+		for _, name := range []string{"keys/a", "keys/b", "c"} {
+			if contents, ok := files[name]; ok {
+				tw.WriteHeader(&tar.Header{Name: ".eris/" + name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(contents)), Uname: "eris"})
+				tw.Write([]byte(contents))
+			}
+		}
+		tw.Close()
+		return tar.NewReader(&buf)
+	}
+
+	first, stored, _, err := storeSnapshotVolume(volume(map[string]string{"keys/a": "one", "keys/b": "two"}))
+	if err != nil || stored != 2 {
+		logger.Errorf("FAILURE: improper first SNAPSHOT. expected: 2 files stored\tgot: %d (%v)\n", stored, err)
+		t.FailNow()
+	}
+	second, stored, _, err := storeSnapshotVolume(volume(map[string]string{"keys/a": "one", "keys/b": "changed", "c": "new"}))
+	if err != nil || stored != 2 {
+		logger.Errorf("FAILURE: unchanged file stored again on SNAPSHOT. expected: 2 files stored\tgot: %d (%v)\n", stored, err)
+		t.FailNow()
+	}
+	objects, _ := filepath.Glob(path.Join(snapshotObjectsPath(), "*", "*"))
+	if len(objects) != 4 {
+		logger.Errorf("FAILURE: improper object count. expected: 4\tgot: %d\n", len(objects))
+		t.Fail()
+	}
+
+	for _, c := range []struct {
+		entries []*SnapshotEntry
+		files   map[string]string
+	}{
+		{first, map[string]string{"keys/": "", "keys/a": "one", "keys/b": "two"}},
+		{second, map[string]string{"keys/": "", "keys/a": "one", "keys/b": "changed", "c": "new"}},
+	} {
+		var buf bytes.Buffer
+		if err := writeSnapshotTar(&buf, c.entries); err != nil {
+			logger.Errorln(err)
+			t.FailNow()
+		}
+		got := map[string]string{}
+		reader := tar.NewReader(&buf)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				logger.Errorln(err)
+				t.FailNow()
+			}
+			if header.Uname != "eris" {
+				logger.Errorf("FAILURE: owner lost on ROLLBACK of %s. expected: eris\tgot: %s\n", header.Name, header.Uname)
+				t.Fail()
+			}
+			raw, _ := ioutil.ReadAll(reader)
+			got[header.Name] = string(raw)
+		}
+		for name, contents := range c.files {
+			if got[name] != contents {
+				logger.Errorf("FAILURE: improper contents on ROLLBACK of %s. expected: %s\tgot: %s\n", name, contents, got[name])
+				t.Fail()
+			}
+		}
+		if len(got) != len(c.files) {
+			logger.Errorf("FAILURE: improper files on ROLLBACK. expected: %v\tgot: %v\n", c.files, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestSnapshotNames(t *testing.T) {
+	for _, c := range []struct{ name, tag string }{
+		{"keys", "../../../etc/x"},
+		{"keys", ".."},
+		{"keys", `a\b`},
+		{"../keys", "tag"},
+		{"..", "tag"},
+		{"", "tag"},
+	} {
+		do := definitions.NowDo()
+		do.Name, do.Tag = c.name, c.tag
+		if err := RollbackData(do); err == nil || !strings.Contains(err.Error(), "snapshot") {
+			logger.Errorf("FAILURE: expected ROLLBACK to refuse %s:%s, got: %v\n", c.name, c.tag, err)
+			t.Fail()
+		}
+		if err := SnapshotData(do); err == nil || !strings.Contains(err.Error(), "snapshot") {
+			logger.Errorf("FAILURE: expected SNAPSHOT to refuse %s:%s, got: %v\n", c.name, c.tag, err)
+			t.Fail()
+		}
+	}
+	if _, err := loadSnapshots(".."); err == nil {
+		logger.Errorf("FAILURE: expected the snapshots of .. to be refused\n")
+		t.Fail()
+	}
+
+	entries := []*SnapshotEntry{{Name: "a", Type: tar.TypeReg, Size: 1, Hash: "../../../etc/passwd"}}
+	if err := writeSnapshotTar(ioutil.Discard, entries); err == nil {
+		logger.Errorf("FAILURE: expected a bad content hash to be refused on ROLLBACK\n")
+		t.Fail()
+	}
+}
+
+func TestSyncDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_sync_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "contracts", ".git"), 0755)
+	ioutil.WriteFile(path.Join(dir, "config.toml"), []byte("moniker = \"one\""), 0644)
+	ioutil.WriteFile(path.Join(dir, "contracts", "a.sol"), []byte("contract A {}"), 0644)
+	ioutil.WriteFile(path.Join(dir, "contracts", "a.sol~"), []byte("backup"), 0644)
+	ioutil.WriteFile(path.Join(dir, "contracts", ".git", "HEAD"), []byte("ref"), 0644)
+	ioutil.WriteFile(path.Join(dir, "node.log"), []byte("log"), 0644)
+
+	ignores := append(defaultSyncIgnores, "*.log")
+	before, err := scanHostDir(dir, ignores)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if names := strings.Join(sortedNames(before), ","); names != "config.toml,contracts/a.sol" {
+		logger.Errorf("FAILURE: improper files on SYNC. expected: config.toml,contracts/a.sol\tgot: %s\n", names)
+		t.Fail()
+	}
+
+	ioutil.WriteFile(path.Join(dir, "config.toml"), []byte("moniker = \"two!\""), 0644)
+	os.Remove(path.Join(dir, "contracts", "a.sol"))
+	ioutil.WriteFile(path.Join(dir, "contracts", "b.sol"), []byte("contract B {}"), 0644)
+	ioutil.WriteFile(path.Join(dir, "node.log"), []byte("more log"), 0644)
+
+	after, err := scanHostDir(dir, ignores)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	changed, removed := diffHostFiles(before, after)
+	if got := strings.Join(changed, ","); got != "config.toml,contracts/b.sol" {
+		logger.Errorf("FAILURE: improper changes on SYNC. expected: config.toml,contracts/b.sol\tgot: %s\n", got)
+		t.Fail()
+	}
+	if got := strings.Join(removed, ","); got != "contracts/a.sol" {
+		logger.Errorf("FAILURE: improper removals on SYNC. expected: contracts/a.sol\tgot: %s\n", got)
+		t.Fail()
+	}
+	// symlinks are followed, the same as import follows them
+	os.Symlink(path.Join(dir, "config.toml"), path.Join(dir, "link.toml"))
+	os.Symlink(path.Join(dir, "contracts"), path.Join(dir, "linked"))
+	linked, err := scanHostDir(dir, ignores)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	expected := "config.toml,contracts/b.sol,link.toml,linked/b.sol"
+	if names := strings.Join(sortedNames(linked), ","); names != expected {
+		logger.Errorf("FAILURE: improper symlinked files on SYNC. expected: %s\tgot: %s\n", expected, names)
+		t.Fail()
+	}
+}
+
 func testExist(t *testing.T, name string, toExist bool) {
 	var exist bool
 	logger.Infof("\nTesting whether (%s) existing? (%t)\n", name, toExist)
@@ -241,3 +429,205 @@ func ifExit(err error) {
 		os.Exit(1)
 	}
 }
+
+func TestDataDiff(t *testing.T) {
+	file := func(contents string) *dataFile {
+		f, _ := readDataFile(strings.NewReader(contents), int64(len(contents)), true)
+		return f
+	}
+	cont := map[string]*dataFile{
+		"config.toml":   file("moniker = \"one\"\nfast_sync = true\n"),
+		"genesis.json":  file("{}"),
+		"priv_val.json": file("{\"address\": \"AB\"}"),
+	}
+	host := map[string]*dataFile{
+		"config.toml":     file("moniker = \"two\"\nfast_sync = true\n"),
+		"genesis.json":    file("{}"),
+		"contracts/a.sol": file("contract A {}"),
+	}
+
+	added, removed, modified := diffDataFiles(cont, host)
+	for _, c := range []struct{ what, expected, got string }{
+		{"added", "contracts/a.sol", strings.Join(added, ",")},
+		{"removed", "priv_val.json", strings.Join(removed, ",")},
+		{"modified", "config.toml", strings.Join(modified, ",")},
+	} {
+		if c.got != c.expected {
+			logger.Errorf("FAILURE: improper %s files on DIFF. expected: %s\tgot: %s\n", c.what, c.expected, c.got)
+			t.Fail()
+		}
+	}
+
+	a := strings.Split("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12", "\n")
+	b := strings.Split("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13", "\n")
+	expected := `@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if got := unifiedDiff(lineDiff(a, b)); got != expected {
+		logger.Errorf("FAILURE: improper content DIFF. expected:\n%s\tgot:\n%s\n", expected, got)
+		t.Fail()
+	}
+
+	if got := contentDiff("bin", file("a\x00b"), file("a\x00c")); !strings.HasSuffix(got, "(binary files differ)\n") {
+		logger.Errorf("FAILURE: improper binary DIFF. expected: (binary files differ)\tgot: %s\n", got)
+		t.Fail()
+	}
+}
+
+func TestPruneHostDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_prune_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "kept", "sub"), 0755)
+	os.MkdirAll(path.Join(dir, "gone", "sub"), 0755)
+	ioutil.WriteFile(path.Join(dir, "gone", "a"), make([]byte, 1000), 0644)
+	ioutil.WriteFile(path.Join(dir, "gone", "sub", "b"), make([]byte, 24), 0644)
+	ioutil.WriteFile(path.Join(dir, "stray.txt"), []byte("not a folder"), 0644)
+
+	dirs, err := orphanHostDirs(dir, map[string]bool{"kept": true})
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if len(dirs) != 1 || dirs["gone"] != 1024 {
+		logger.Errorf("FAILURE: improper folders to PRUNE. expected: map[gone:1024]\tgot: %v\n", dirs)
+		t.Fail()
+	}
+
+	for size, expected := range map[int64]string{512: "512 B", 1024: "1.0 KiB", 1536 * 1024: "1.5 MiB"} {
+		if got := humanSize(size); got != expected {
+			logger.Errorf("FAILURE: improper SIZE. expected: %s\tgot: %s\n", expected, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundle/genesis.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "eris_fetch_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	// sha256 of {}
+	sum := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := fetchSource(server.URL+"/bundle/genesis.json#sha256="+sum, dir, true); err != nil {
+		logger.Errorf("FAILURE: could not FETCH: %v\n", err)
+		t.FailNow()
+	}
+	if raw, _ := ioutil.ReadFile(path.Join(dir, "genesis.json")); string(raw) != "{}" {
+		logger.Errorf("FAILURE: improper FETCH. expected: {}\tgot: %s\n", raw)
+		t.Fail()
+	}
+
+	for _, source := range []string{
+		server.URL + "/bundle/genesis.json#sha256=" + strings.Repeat("0", 64),
+		server.URL + "/bundle/missing.json",
+		server.URL + "/bundle/genesis.json",
+	} {
+		if err := fetchSource(source, dir, true); err == nil {
+			logger.Errorf("FAILURE: FETCH of %s should fail\n", source)
+			t.Fail()
+		}
+	}
+
+	if err := fetchSource(server.URL+"/bundle/genesis.json", dir, false); err != nil {
+		logger.Errorf("FAILURE: could not FETCH unchecked: %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestFetchIPFS(t *testing.T) {
+	node := ipfs.NewFake()
+	defer func(n ipfs.IPFS) { ipfs.Node = n }(ipfs.Node)
+	ipfs.Node = node
+
+	src, err := ioutil.TempDir("", "eris_fetch_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(src)
+	os.MkdirAll(filepath.Join(src, "chains", "test"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "chains", "test", "genesis.json"), []byte("{}"), 0644)
+
+	hash, err := node.AddDir(src, nil)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	dir, err := ioutil.TempDir("", "eris_fetch_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	if err := fetchSource("ipfs:"+hash, dir, true); err != nil {
+		logger.Errorf("FAILURE: could not FETCH: %v\n", err)
+		t.FailNow()
+	}
+	if raw, _ := ioutil.ReadFile(filepath.Join(dir, "chains", "test", "genesis.json")); string(raw) != "{}" {
+		logger.Errorf("FAILURE: improper FETCH. expected: {}\tgot: %s\n", raw)
+		t.Fail()
+	}
+
+	if err := fetchSource("ipfs:QmMissing", dir, true); err == nil {
+		logger.Errorf("FAILURE: FETCH of a missing hash should fail\n")
+		t.Fail()
+	}
+
+	escape := &escapingNode{Fake: node, root: hash, name: "../escaped.json"}
+	defer os.Remove(filepath.Join(filepath.Dir(dir), "escaped.json"))
+	ipfs.Node = escape
+	if err := fetchSource("ipfs:"+hash, dir, true); err == nil {
+		logger.Errorf("FAILURE: FETCH of %s should fail\n", escape.name)
+		t.Fail()
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escaped.json")); !os.IsNotExist(err) {
+		logger.Errorf("FAILURE: FETCH wrote outside its folder\n")
+		t.Fail()
+	}
+}
+
+// escapingNode lists one more link under root, with a name chosen to
+// leave the folder it is fetched into.
+type escapingNode struct {
+	*ipfs.Fake
+	root string
+	name string
+}
+
+func (n *escapingNode) Ls(hash string) ([]ipfs.Link, error) {
+	links, err := n.Fake.Ls(hash)
+	if err != nil || hash != n.root || len(links) == 0 {
+		return links, err
+	}
+	return append(links, ipfs.Link{Name: n.name, Hash: links[0].Hash, Type: ipfs.File}), nil
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// largest file whose changes eris data diff --content shows
+const maxContentDiff = 1 << 20
+
+// longest pair of files, in lines multiplied, the line diff takes on
+const maxDiffCells = 4 << 20
+
+// lines of context around each change
+const diffContext = 3
+
+// DiffData compares ~/.eris/data/do.Name on the host with do.Path
+// (/home/eris/.eris by default) in the data container by hash and size
+// and lists the files added on the host (A), missing from the host (D),
+// and different on the two sides (M). With do.Content the changes to
+// text files are shown as well, container first.
+func DiffData(do *definitions.Do) error {
+	containerName, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if containerName == "" {
+		return fmt.Errorf("I cannot find that data container. Please check the data container name you sent me.")
+	}
+	hostDir := filepath.Join(DataContainersPath, do.Name)
+	if do.Path == "" {
+		do.Path = "/home/eris/.eris"
+	}
+
+	host, err := hostFiles(hostDir, do.Content)
+	if err != nil {
+		return err
+	}
+	cont, err := containerFiles(containerName, do.Path, do.Content)
+	if err != nil {
+		return err
+	}
+
+	added, removed, modified := diffDataFiles(cont, host)
+	if len(added)+len(removed)+len(modified) == 0 {
+		logger.Println("No differences.")
+		do.Result = ""
+		return nil
+	}
+
+	lines := []string{}
+	for _, name := range added {
+		lines = append(lines, fmt.Sprintf("A  %s (%d bytes)", name, host[name].Size))
+	}
+	for _, name := range removed {
+		lines = append(lines, fmt.Sprintf("D  %s (%d bytes)", name, cont[name].Size))
+	}
+	for _, name := range modified {
+		lines = append(lines, fmt.Sprintf("M  %s (%d -> %d bytes)", name, cont[name].Size, host[name].Size))
+	}
+	sort.Sort(byDiffName(lines))
+	for _, line := range lines {
+		logger.Println(line)
+	}
+
+	if do.Content {
+		for _, name := range modified {
+			logger.Printf("\n%s", contentDiff(name, cont[name], host[name]))
+		}
+	}
+
+	do.Result = strings.Join(lines, "\n")
+	return nil
+}
+
+// diffDataFiles compares the files in a container with those on the
+// host.
+func diffDataFiles(cont, host map[string]*dataFile) (added, removed, modified []string) {
+	added, removed, modified = []string{}, []string{}, []string{}
+	for name, file := range host {
+		c, ok := cont[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case c.Size != file.Size || c.Hash != file.Hash:
+			modified = append(modified, name)
+		}
+	}
+	for name := range cont {
+		if _, ok := host[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+func contentDiff(name string, cont, host *dataFile) string {
+	header := fmt.Sprintf("--- container/%s\n+++ host/%s\n", name, name)
+	switch {
+	case cont.Contents == nil || host.Contents == nil:
+		return header + "(too large to show)\n"
+	case !isText(cont.Contents) || !isText(host.Contents):
+		return header + "(binary files differ)\n"
+	}
+
+	a, b := splitLines(cont.Contents), splitLines(host.Contents)
+	if len(a)*len(b) > maxDiffCells {
+		return header + "(too long to show)\n"
+	}
+	return header + unifiedDiff(lineDiff(a, b))
+}
+
+func isText(raw []byte) bool {
+	return !bytes.Contains(raw, []byte{0}) && utf8.Valid(raw)
+}
+
+func splitLines(raw []byte) []string {
+	if len(raw) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+}
+
+type diffLine struct {
+	Kind byte // ' ', '-' or '+'
+	Text string
+}
+
+// lineDiff finds the shortest edit from a to b through their longest
+// common subsequence of lines.
+func lineDiff(a, b []string) []diffLine {
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// unifiedDiff prints the changes in lines as hunks with a few lines of
+// context, like diff -u.
+func unifiedDiff(lines []diffLine) string {
+	var buf bytes.Buffer
+
+	// where each line sits in the two files
+	aAt, bAt := make([]int, len(lines)), make([]int, len(lines))
+	a, b := 1, 1
+	for i, line := range lines {
+		aAt[i], bAt[i] = a, b
+		if line.Kind != '+' {
+			a++
+		}
+		if line.Kind != '-' {
+			b++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while the next change is close enough to
+		// share context
+		end, quiet := i, 0
+		for end < len(lines) && quiet <= 2*diffContext {
+			if lines[end].Kind == ' ' {
+				quiet++
+			} else {
+				quiet = 0
+			}
+			end++
+		}
+		if quiet > diffContext {
+			end -= quiet - diffContext
+		}
+
+		var aLen, bLen int
+		for _, line := range lines[start:end] {
+			if line.Kind != '+' {
+				aLen++
+			}
+			if line.Kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aAt[start], aLen, bAt[start], bLen)
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(&buf, "%c%s\n", line.Kind, line.Text)
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// sorts A, D and M lines by file name
+type byDiffName []string
+
+func (s byDiffName) Len() int           { return len(s) }
+func (s byDiffName) Less(i, j int) bool { return s[i][3:] < s[j][3:] }
+func (s byDiffName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
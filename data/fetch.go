@@ -0,0 +1,109 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/ipfs"
+)
+
+// isRemoteSource tells an IPFS hash or URL data import fetches from
+// apart from a host folder.
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "ipfs:") || strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetchSource downloads an ipfs:<hash> file or directory, or a URL,
+// into dir and checks it is what was asked for. IPFS files are checked
+// against their hashes. A URL is checked against the sha256 in its
+// fragment (https://host/file#sha256=<hex>), which it must have unless
+// verify is false.
+func fetchSource(source, dir string, verify bool) error {
+	if strings.HasPrefix(source, "ipfs:") {
+		return fetchIPFS(strings.TrimPrefix(source, "ipfs:"), dir)
+	}
+	return fetchURL(source, dir, verify)
+}
+
+func fetchIPFS(hash, dir string) error {
+	entries, err := ipfs.Walk(ipfs.Node, hash)
+	if err != nil {
+		return fmt.Errorf("The marmots could not list %s in IPFS: %v", hash, err)
+	}
+	for _, entry := range entries {
+		// a lone file is named after its hash
+		name := entry.Path
+		if name == "" {
+			name = entry.Hash
+		}
+		fileName, err := ipfs.LocalPath(dir, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+
+		logger.Infof("Fetching =>\t\t\t%s:%s\n", entry.Hash, name)
+		// checked against its hash as it is fetched
+		if err := ipfs.Node.Get(entry.Hash, fileName); err != nil {
+			return fmt.Errorf("The marmots could not get %s from IPFS: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func fetchURL(source, dir string, verify bool) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("The marmots cannot read the URL (%s): %v", source, err)
+	}
+	want := strings.TrimPrefix(u.Fragment, "sha256=")
+	u.Fragment = ""
+	if want == "" && verify {
+		return fmt.Errorf("The marmots will not import %s unchecked. Add its hash (%s#sha256=<hex>) or use --skip-verify.", u.String(), u.String())
+	}
+
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || name == ".." {
+		name = u.Host
+	}
+	output, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	logger.Infof("Fetching =>\t\t\t%s\n", u.String())
+	response, err := http.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("The marmots could not get %s: %s", u.String(), response.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(output, hash), response.Body); err != nil {
+		return err
+	}
+	got := hex.EncodeToString(hash.Sum(nil))
+
+	if want == "" {
+		logger.Printf("Fetched unchecked =>\t\t%s (sha256 %s)\n", name, got)
+		return nil
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("The content of %s does not match its hash. Expected sha256 %s, got %s.", u.String(), want, got)
+	}
+	return nil
+}
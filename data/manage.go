@@ -77,8 +77,25 @@ func RmData(do *definitions.Do) error {
 	return nil
 }
 
+// ListKnown lists the data containers, and the services and chains
+// which keep their data in named volumes instead.
 func ListKnown(do *definitions.Do) error {
-	do.Result = strings.Join(util.DataContainerNames(), "\n")
+	names := util.DataContainerNames()
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	owners, err := util.DataVolumeOwners()
+	if err != nil {
+		logger.Debugf("Could not list volumes =>\t%v\n", err)
+	}
+	for _, c := range owners {
+		if name := strings.Replace(c.ShortName, "_", " ", -1); !known[name] {
+			known[name] = true
+			names = append(names, name)
+		}
+	}
+	do.Result = strings.Join(names, "\n")
 	return nil
 }
 
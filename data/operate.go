@@ -3,64 +3,160 @@ package data
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/util"
 
-	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/ebuchman/go-shell-pipes"
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 )
 
+// ImportData copies the contents of ~/.eris/data/do.Name on the host
+// into do.Path (/home/eris/.eris by default) of the data container,
+// making the container first if need be. The files are streamed through
+// the Docker API, so no docker binary is needed and remote Docker hosts
+// work. Modes are kept and the files are owned by the user the data
+// container runs as (eris unless it says otherwise). With do.Source the
+// files come from an IPFS hash or URL instead, checked against their
+// hashes before anything reaches the container.
 func ImportData(do *definitions.Do) error {
-	if util.IsDataContainer(do.Name, do.Operations.ContainerNumber) {
-
-		containerName := util.DataContainersName(do.Name, do.Operations.ContainerNumber)
-		importPath := filepath.Join(DataContainersPath, do.Name)
-
-		// temp until docker cp works both ways.
-		logger.Debugf("Importing FROM =>\t\t%s\n", importPath)
-		os.Chdir(importPath)
-		// TODO [eb]: deal with hardcoded user
-		// TODO [csk]: drop the whole damn cmd call
-		//         use go's tar lib to make a tarball of the directory
-		//         read the tar file into an io.Reader
-		//         start a container with its Stdin open, connect to an io.Writer
-		//         connect them up with io.Pipe
-		//         this will free us from any quirks that the cli has
-		if do.Path != "" {
-			do.Path = do.Path
-		} else {
-			do.Path = "/home/eris/.eris"
+	importPath := filepath.Join(DataContainersPath, do.Name)
+	if do.Source != "" {
+		if !isRemoteSource(do.Source) {
+			return fmt.Errorf("The marmots can import from ipfs:<hash>, http:// or https:// sources, not %s.", do.Source)
 		}
-
-		logger.Debugf("Importing TO =>\t\t\t%s\n", do.Path)
-		cmd := "tar chf - . | docker run -i --rm --volumes-from " + containerName + " --user eris eris/data tar xf - -C " + do.Path
-		_, err := pipes.RunString(cmd)
+		dir, err := ioutil.TempDir("", "eris_import_")
 		if err != nil {
-			cmd := "tar chf - . | docker run -i --volumes-from " + containerName + " --user eris eris/data tar xf - -C " + do.Path
-			_, e2 := pipes.RunString(cmd)
-			if e2 != nil {
-				return fmt.Errorf("Could not import the data container.\nTried with docker --rm =>\t%v\nTried without docker --rm =>\t%v", err, e2)
-			}
+			return err
 		}
-	} else {
+		defer os.RemoveAll(dir)
+		if err := fetchSource(do.Source, dir, !do.SkipVerify); err != nil {
+			return err
+		}
+		importPath = dir
+	}
+
+	containerName, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if containerName == "" {
 		if err := perform.DockerCreateDataContainer(do.Name, do.Operations.ContainerNumber); err != nil {
 			return fmt.Errorf("Error creating data container %v.", err)
 		}
-		return ImportData(do)
+		containerName = util.DataContainersName(do.Name, do.Operations.ContainerNumber)
+	}
+	if do.Path == "" {
+		do.Path = "/home/eris/.eris"
+	}
+
+	owner, err := dataOwner(containerName)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Importing FROM =>\t\t%s\n", importPath)
+	logger.Debugf("Importing TO =>\t\t\t%s\n", do.Path)
+	reader, writer := io.Pipe()
+	tarred := make(chan error, 1)
+	go func() {
+		err := util.TarDirectory(importPath, owner, writer)
+		writer.CloseWithError(err)
+		tarred <- err
+	}()
+
+	err = perform.DockerImportTar(containerName, do.Path, reader)
+	// unblocks the tar writer if the import gave up early
+	reader.Close()
+	if tarErr := <-tarred; tarErr != nil && tarErr != io.ErrClosedPipe {
+		return fmt.Errorf("Could not read the data to import from %s: %v", importPath, tarErr)
+	}
+	if err != nil {
+		return fmt.Errorf("Could not import the data container: %v", err)
 	}
+
 	do.Result = "success"
 	return nil
 }
 
+// HoldData finds the container holding the data of name, as
+// util.FindDataHolder does. The named volumes of a service or chain
+// with data_volumes set which no container mounts get a holder
+// container, made from the definition for the moment and removed by
+// release; release is never nil. The name is "" when there is no data
+// container and no definition with data_volumes.
+func HoldData(name string, number int) (string, func(), error) {
+	release := func() {}
+	if holder := util.FindDataHolder(name, number); holder != "" {
+		return holder, release, nil
+	}
+
+	srv, ops := volumesDefinition(name, number)
+	if srv == nil {
+		return "", release, nil
+	}
+	binds, err := perform.DataVolumeBinds(srv, ops)
+	if err != nil {
+		return "", release, err
+	}
+
+	holder := util.VolumesHolderName(name, number)
+	perform.DockerReleaseVolumes(holder) // left behind by a crash
+	if err := perform.DockerHoldVolumes(holder, srv.User, binds); err != nil {
+		return "", release, fmt.Errorf("The marmots could not reach the data volumes of %s: %v", name, err)
+	}
+	release = func() {
+		if err := perform.DockerReleaseVolumes(holder); err != nil {
+			logger.Infof("Could not remove =>\t\t%s: %v\n", holder, err)
+		}
+	}
+	return holder, release, nil
+}
+
+// volumesDefinition loads the service, or else the chain, name if it
+// keeps its data in named volumes.
+func volumesDefinition(name string, number int) (*definitions.Service, *definitions.Operation) {
+	if util.GetFileByNameAndType("services", name) != "" {
+		if s, err := loaders.LoadServiceDefinition(name, false, number); err == nil && s.Service.DataVolumes {
+			return s.Service, s.Operations
+		}
+	}
+	if util.GetFileByNameAndType("chains", name) != "" {
+		if c, err := loaders.LoadChainDefinition(name, false, number); err == nil && c.Service.DataVolumes {
+			return c.Service, c.Operations
+		}
+	}
+	return nil, nil
+}
+
+// dataOwner is the user a data container runs as, eris unless the
+// container says otherwise.
+func dataOwner(containerName string) (string, error) {
+	cont, err := util.DockerClient.InspectContainer(containerName)
+	if err != nil {
+		return "", err
+	}
+	if cont.Config != nil && cont.Config.User != "" {
+		return cont.Config.User, nil
+	}
+	return "eris", nil
+}
+
 func ExecData(do *definitions.Do) error {
-	if util.IsDataContainer(do.Name, do.Operations.ContainerNumber) {
-		do.Name = util.DataContainersName(do.Name, do.Operations.ContainerNumber)
+	holder, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if holder != "" {
+		do.Name = holder
 		logger.Infoln("Running exec on container with volumes from data container " + do.Name)
 		if err := perform.DockerRunVolumesFromContainer(do.Name, do.Interactive, do.Args); err != nil {
 			return err
@@ -73,23 +169,24 @@ func ExecData(do *definitions.Do) error {
 }
 
 func ExportData(do *definitions.Do) error {
-	if util.IsDataContainer(do.Name, do.Operations.ContainerNumber) {
+	holder, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if holder != "" {
 		logger.Infoln("Exporting data container", do.Name)
 
 		exportPath := filepath.Join(DataContainersPath, do.Name) // TODO: do.Operations.ContainerNumber ?
-		srv := PretendToBeAService(do.Name, do.Operations.ContainerNumber)
-
-		service, exists := perform.ContainerExists(srv.Operations)
-
-		if !exists {
-			return fmt.Errorf("There is no data container for that service.")
+		if do.Destination != "" {
+			exportPath = do.Destination
 		}
-		logger.Infoln("Service ID: " + service.ID)
 
-		cont, err := util.DockerClient.InspectContainer(service.ID)
+		cont, err := util.DockerClient.InspectContainer(holder)
 		if err != nil {
 			return err
 		}
+		logger.Infoln("Service ID: " + cont.ID)
 
 		reader, writer := io.Pipe()
 
@@ -100,7 +197,7 @@ func ExportData(do *definitions.Do) error {
 		}
 		opts := docker.CopyFromContainerOptions{
 			OutputStream: writer,
-			Container:    service.ID,
+			Container:    cont.ID,
 			Resource:     do.Path,
 		}
 
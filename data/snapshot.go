@@ -0,0 +1,482 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// Snapshot is the manifest of a data container's volumes at one point
+// in time. File contents live once in the object store, keyed by
+// their sha256, however many snapshots share them.
+type Snapshot struct {
+	Name    string            `json:"name"`
+	Tag     string            `json:"tag"`
+	Created time.Time         `json:"created"`
+	Volumes []*SnapshotVolume `json:"volumes"`
+}
+
+type SnapshotVolume struct {
+	Path    string           `json:"path"`
+	Entries []*SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry keeps the tar header of one file, directory or link,
+// with the names relative to the volume.
+type SnapshotEntry struct {
+	Name     string    `json:"name"`
+	Type     byte      `json:"type"`
+	Mode     int64     `json:"mode"`
+	Uid      int       `json:"uid"`
+	Gid      int       `json:"gid"`
+	Uname    string    `json:"uname,omitempty"`
+	Gname    string    `json:"gname,omitempty"`
+	ModTime  time.Time `json:"mod_time"`
+	Size     int64     `json:"size"`
+	Linkname string    `json:"linkname,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+}
+
+// SnapshotsPath holds the snapshot manifests, one directory per data
+// container, and the objects directory they share.
+func SnapshotsPath() string {
+	return path.Join(ErisRoot, "snapshots")
+}
+
+func snapshotObjectsPath() string {
+	return path.Join(SnapshotsPath(), "objects")
+}
+
+// snapshotFile is where the manifest of a snapshot is kept. Names and
+// tags which would lead anywhere else are refused.
+func snapshotFile(name, tag string) (string, error) {
+	if err := checkSnapshotPart("name", name); err != nil {
+		return "", err
+	}
+	if err := checkSnapshotPart("tag", tag); err != nil {
+		return "", err
+	}
+	return path.Join(SnapshotsPath(), name, tag+".json"), nil
+}
+
+func checkSnapshotPart(what, value string) error {
+	if value == "" || value == "." || value == ".." || strings.ContainsAny(value, `/\`) {
+		return fmt.Errorf("The marmots cannot use (%s) as a snapshot %s. It cannot be empty, . or .. or contain slashes.", value, what)
+	}
+	return nil
+}
+
+// SnapshotData saves the volumes of the data container do.Name as the
+// snapshot do.Tag (the time, by default).
+func SnapshotData(do *definitions.Do) error {
+	if do.Tag == "" {
+		do.Tag = time.Now().UTC().Format("20060102T150405Z")
+	}
+	fileName, err := snapshotFile(do.Name, do.Tag)
+	if err != nil {
+		return err
+	}
+
+	containerName, volumes, release, err := dataVolumes(do)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := os.Stat(fileName); err == nil && !do.Force {
+		return fmt.Errorf("%s already has a snapshot tagged %s. Use --force to replace it.", do.Name, do.Tag)
+	}
+
+	snap := &Snapshot{Name: do.Name, Tag: do.Tag, Created: time.Now().UTC()}
+	var stored, bytesStored int64
+	for _, vol := range volumes {
+		logger.Infof("Snapshotting volume =>\t%s:%s\n", containerName, vol)
+		reader, writer := io.Pipe()
+		copied := make(chan error, 1)
+		go func() {
+			err := util.DockerClient.CopyFromContainer(docker.CopyFromContainerOptions{
+				OutputStream: writer,
+				Container:    containerName,
+				Resource:     vol,
+			})
+			writer.CloseWithError(err)
+			copied <- err
+		}()
+
+		entries, n, size, err := storeSnapshotVolume(tar.NewReader(reader))
+		reader.Close()
+		if copyErr := <-copied; copyErr != nil && copyErr != io.ErrClosedPipe {
+			return fmt.Errorf("The marmots could not copy %s out of %s: %v", vol, containerName, copyErr)
+		}
+		if err != nil {
+			return err
+		}
+		snap.Volumes = append(snap.Volumes, &SnapshotVolume{Path: vol, Entries: entries})
+		stored += n
+		bytesStored += size
+	}
+
+	if err := writeSnapshot(snap); err != nil {
+		return err
+	}
+	logger.Printf("Snapshot taken =>\t\t%s:%s (%d new files, %d bytes)\n", do.Name, do.Tag, stored, bytesStored)
+	do.Result = "success"
+	return nil
+}
+
+// ListSnapshots prints the snapshots of the data container do.Name,
+// oldest first.
+func ListSnapshots(do *definitions.Do) error {
+	snaps, err := loadSnapshots(do.Name)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		logger.Printf("%s has no snapshots. Take one with [eris data snapshot %s].\n", do.Name, do.Name)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tCREATED\tFILES\tSIZE")
+	tags := []string{}
+	for _, snap := range snaps {
+		files, size := snap.count()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", snap.Tag, snap.Created.Local().Format(time.Stamp), files, size)
+		tags = append(tags, snap.Tag)
+	}
+	w.Flush()
+	logger.Printf("%s", buf.String())
+
+	do.Result = strings.Join(tags, "\n")
+	return nil
+}
+
+// RollbackData puts the volumes of the data container do.Name back the
+// way they were in the snapshot do.Tag. Files added since are removed.
+// The service or chain using the container has to be stopped first
+// unless do.Force is given.
+func RollbackData(do *definitions.Do) error {
+	fileName, err := snapshotFile(do.Name, do.Tag)
+	if err != nil {
+		return err
+	}
+
+	containerName, _, release, err := dataVolumes(do)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	snap, err := loadSnapshot(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s has no snapshot tagged %s. See [eris data snapshots %s].", do.Name, do.Tag, do.Name)
+		}
+		return err
+	}
+
+	num := do.Operations.ContainerNumber
+	if !do.Force && (util.IsServiceContainer(do.Name, num, true) || util.IsChainContainer(do.Name, num, true)) {
+		return fmt.Errorf("%s is running. Stop it before rolling its data back, or use --force.", do.Name)
+	}
+
+	for _, vol := range snap.Volumes {
+		logger.Infof("Restoring volume =>\t\t%s:%s\n", containerName, vol.Path)
+		reader, writer := io.Pipe()
+		tarred := make(chan error, 1)
+		go func(entries []*SnapshotEntry) {
+			err := writeSnapshotTar(writer, entries)
+			writer.CloseWithError(err)
+			tarred <- err
+		}(vol.Entries)
+
+		err := perform.DockerReplaceTar(containerName, vol.Path, reader)
+		reader.Close()
+		if tarErr := <-tarred; tarErr != nil && tarErr != io.ErrClosedPipe {
+			return fmt.Errorf("The marmots could not read snapshot %s: %v", do.Tag, tarErr)
+		}
+		if err != nil {
+			return fmt.Errorf("The marmots could not restore %s: %v", vol.Path, err)
+		}
+	}
+
+	logger.Printf("Rolled back =>\t\t\t%s:%s\n", do.Name, do.Tag)
+	do.Result = "success"
+	return nil
+}
+
+// dataVolumes finds the container holding the data of do.Name and the
+// volumes it holds (see HoldData).
+func dataVolumes(do *definitions.Do) (string, []string, func(), error) {
+	containerName, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return "", nil, release, err
+	}
+	if containerName == "" {
+		return "", nil, release, fmt.Errorf("I cannot find that data container. Please check the data container name you sent me.")
+	}
+
+	volumes, err := containerVolumes(containerName)
+	if err == nil && len(volumes) == 0 {
+		err = fmt.Errorf("The data container of %s has no volumes to snapshot.", do.Name)
+	}
+	if err != nil {
+		release()
+		return "", nil, func() {}, err
+	}
+	return containerName, volumes, release, nil
+}
+
+// containerVolumes lists the volume paths of a container.
+func containerVolumes(containerName string) ([]string, error) {
+	cont, err := util.DockerClient.InspectContainer(containerName)
+	if err != nil {
+		return nil, err
+	}
+	// a service or chain with its data in named volumes
+	if volumes := util.DataVolumeMounts(cont); len(volumes) != 0 {
+		sort.Strings(volumes)
+		return volumes, nil
+	}
+	volumes := []string{}
+	if cont.Config != nil {
+		for vol := range cont.Config.Volumes {
+			volumes = append(volumes, vol)
+		}
+	}
+	if len(volumes) == 0 {
+		for vol := range cont.Volumes {
+			volumes = append(volumes, vol)
+		}
+	}
+	sort.Strings(volumes)
+	return volumes, nil
+}
+
+// storeSnapshotVolume reads a volume as docker copies it out (every
+// name starting with the volume's directory) into the object store.
+// It returns the entries and how many files and bytes were new.
+func storeSnapshotVolume(reader *tar.Reader) ([]*SnapshotEntry, int64, int64, error) {
+	if err := os.MkdirAll(snapshotObjectsPath(), 0755); err != nil {
+		return nil, 0, 0, err
+	}
+
+	entries := []*SnapshotEntry{}
+	var stored, size int64
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		name := stripVolumeDir(header.Name)
+		if name == "" {
+			continue
+		}
+		entry := &SnapshotEntry{
+			Name:     name,
+			Type:     header.Typeflag,
+			Mode:     header.Mode,
+			Uid:      header.Uid,
+			Gid:      header.Gid,
+			Uname:    header.Uname,
+			Gname:    header.Gname,
+			ModTime:  header.ModTime,
+			Size:     header.Size,
+			Linkname: header.Linkname,
+		}
+		if header.Typeflag == tar.TypeLink {
+			entry.Linkname = stripVolumeDir(header.Linkname)
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			var isNew bool
+			if entry.Hash, isNew, err = storeObject(reader); err != nil {
+				return nil, 0, 0, err
+			}
+			if isNew {
+				stored++
+				size += header.Size
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, stored, size, nil
+}
+
+// the first path element is the volume's own directory
+func stripVolumeDir(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(name[i+1:], "/")
+}
+
+// storeObject saves the contents of reader under their hash unless an
+// identical file is already stored.
+func storeObject(reader io.Reader) (string, bool, error) {
+	tmp, err := ioutil.TempFile(snapshotObjectsPath(), "tmp_")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), reader); err != nil {
+		tmp.Close()
+		return "", false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", false, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	object := objectFile(sum)
+	if _, err := os.Stat(object); err == nil {
+		return sum, false, nil
+	}
+	if err := os.MkdirAll(path.Dir(object), 0755); err != nil {
+		return "", false, err
+	}
+	return sum, true, os.Rename(tmp.Name(), object)
+}
+
+func objectFile(sum string) string {
+	return path.Join(snapshotObjectsPath(), sum[:2], sum)
+}
+
+// writeSnapshotTar writes the entries of a volume as a tar stream for
+// unpacking into the volume.
+func writeSnapshotTar(writer io.Writer, entries []*SnapshotEntry) error {
+	tw := tar.NewWriter(writer)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.Name,
+			Typeflag: entry.Type,
+			Mode:     entry.Mode,
+			Uid:      entry.Uid,
+			Gid:      entry.Gid,
+			Uname:    entry.Uname,
+			Gname:    entry.Gname,
+			ModTime:  entry.ModTime,
+			Linkname: entry.Linkname,
+		}
+		if entry.Type == tar.TypeDir {
+			header.Name += "/"
+		}
+		if entry.Hash == "" {
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the hash names a file under the objects directory
+		if sum, err := hex.DecodeString(entry.Hash); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("bad content hash of %s (%s)", entry.Name, entry.Hash)
+		}
+		header.Size = entry.Size
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(objectFile(entry.Hash))
+		if err != nil {
+			return fmt.Errorf("missing contents of %s: %v", entry.Name, err)
+		}
+		_, err = io.CopyN(tw, f, entry.Size)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeSnapshot(snap *Snapshot) error {
+	fileName, err := snapshotFile(snap.Name, snap.Tag)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, raw, 0644)
+}
+
+func loadSnapshot(fileName string) (*Snapshot, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{}
+	if err := json.Unmarshal(raw, snap); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the snapshot %s: %v", fileName, err)
+	}
+	return snap, nil
+}
+
+func loadSnapshots(name string) ([]*Snapshot, error) {
+	if err := checkSnapshotPart("name", name); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(path.Join(SnapshotsPath(), name, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	snaps := []*Snapshot{}
+	for _, file := range files {
+		snap, err := loadSnapshot(file)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Sort(snapshotsByCreated(snaps))
+	return snaps, nil
+}
+
+func (snap *Snapshot) count() (files int, size int64) {
+	for _, vol := range snap.Volumes {
+		for _, entry := range vol.Entries {
+			if entry.Hash != "" {
+				files++
+				size += entry.Size
+			}
+		}
+	}
+	return files, size
+}
+
+type snapshotsByCreated []*Snapshot
+
+func (s snapshotsByCreated) Len() int           { return len(s) }
+func (s snapshotsByCreated) Less(i, j int) bool { return s[i].Created.Before(s[j].Created) }
+func (s snapshotsByCreated) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
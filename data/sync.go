@@ -0,0 +1,324 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// how often data sync --watch looks for changes on the host
+var syncInterval = time.Second
+
+// never synced, on top of the --ignore patterns
+var defaultSyncIgnores = []string{".git", "*.swp", "*~", ".DS_Store"}
+
+// hostFile is what a change on the host is noticed by
+type hostFile struct {
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+}
+
+// SyncData brings the data container do.Name up to date with
+// ~/.eris/data/do.Name on the host: files which are new or differ are
+// copied in. With do.Watch it then keeps watching the host folder and
+// copies in every create and change, and removes every file deleted on
+// the host, until interrupted. Files matching do.Ignore are left
+// alone. Files only the container has are never removed by the first
+// pass, as chains keep their own data there.
+func SyncData(do *definitions.Do) error {
+	containerName, release, err := HoldData(do.Name, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if containerName == "" {
+		return fmt.Errorf("I cannot find that data container. Please check the data container name you sent me.")
+	}
+	hostDir := filepath.Join(DataContainersPath, do.Name)
+	if do.Path == "" {
+		do.Path = "/home/eris/.eris"
+	}
+	ignores := append(defaultSyncIgnores, do.Ignore...)
+
+	owner, err := dataOwner(containerName)
+	if err != nil {
+		return err
+	}
+
+	files, err := scanHostDir(hostDir, ignores)
+	if err != nil {
+		return err
+	}
+	inContainer, err := containerFiles(containerName, do.Path, false)
+	if err != nil {
+		return err
+	}
+	changed := []string{}
+	for _, name := range sortedNames(files) {
+		sum, err := hashFile(filepath.Join(hostDir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if file, ok := inContainer[name]; !ok || file.Hash != sum {
+			changed = append(changed, name)
+		}
+	}
+	if err := pushFiles(containerName, hostDir, do.Path, owner, changed); err != nil {
+		return err
+	}
+	logger.Printf("Synced =>\t\t\t%s (%d of %d files copied)\n", do.Name, len(changed), len(files))
+
+	if !do.Watch {
+		do.Result = "success"
+		return nil
+	}
+
+	logger.Printf("Watching =>\t\t\t%s. Stop with Ctrl-C.\n", hostDir)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			do.Result = "success"
+			return nil
+		case <-ticker.C:
+		}
+
+		now, err := scanHostDir(hostDir, ignores)
+		if err != nil {
+			logger.Errorf("The marmots could not read %s: %v\n", hostDir, err)
+			continue
+		}
+		changed, removed := diffHostFiles(files, now)
+		if err := pushFiles(containerName, hostDir, do.Path, owner, changed); err != nil {
+			logger.Errorf("The marmots could not copy %v: %v\n", changed, err)
+			continue
+		}
+		if len(removed) != 0 {
+			if err := perform.DockerRemovePaths(containerName, do.Path, removed); err != nil {
+				logger.Errorf("The marmots could not remove %v: %v\n", removed, err)
+				continue
+			}
+		}
+		for _, name := range changed {
+			logger.Printf("Copied =>\t\t\t%s\n", name)
+		}
+		for _, name := range removed {
+			logger.Printf("Removed =>\t\t\t%s\n", name)
+		}
+		files = now
+	}
+}
+
+// scanHostDir lists the files under dir, relative to it, skipping
+// anything matching ignores. Symlinks are followed as they are when
+// the folder is imported.
+func scanHostDir(dir string, ignores []string) (map[string]hostFile, error) {
+	files := map[string]hostFile{}
+	err := util.WalkDirectory(dir, func(name, file string, info os.FileInfo) error {
+		if syncIgnored(name, ignores) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files[name] = hostFile{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// a pattern matches the whole relative name or any one part of it
+func syncIgnored(name string, ignores []string) bool {
+	for _, pattern := range ignores {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		for _, part := range strings.Split(name, "/") {
+			if ok, _ := filepath.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diffHostFiles returns the files created or changed, and the files
+// removed, between two scans.
+func diffHostFiles(before, after map[string]hostFile) (changed, removed []string) {
+	changed, removed = []string{}, []string{}
+	for name, file := range after {
+		if old, ok := before[name]; !ok || old != file {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+func pushFiles(containerName, hostDir, dest, owner string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	tarred := make(chan error, 1)
+	go func() {
+		err := util.TarFiles(hostDir, names, owner, writer)
+		writer.CloseWithError(err)
+		tarred <- err
+	}()
+
+	err := perform.DockerImportTar(containerName, dest, reader)
+	reader.Close()
+	if tarErr := <-tarred; tarErr != nil && tarErr != io.ErrClosedPipe {
+		return tarErr
+	}
+	return err
+}
+
+// dataFile is a file on either side of a data container.
+type dataFile struct {
+	Size     int64
+	Hash     string
+	Contents []byte
+}
+
+// containerFiles hashes every file under dir in a container, by name
+// relative to dir. With keep the contents of files up to
+// maxContentDiff bytes are kept as well.
+func containerFiles(containerName, dir string, keep bool) (map[string]*dataFile, error) {
+	reader, writer := io.Pipe()
+	copied := make(chan error, 1)
+	go func() {
+		err := util.DockerClient.CopyFromContainer(docker.CopyFromContainerOptions{
+			OutputStream: writer,
+			Container:    containerName,
+			Resource:     dir,
+		})
+		writer.CloseWithError(err)
+		copied <- err
+	}()
+
+	files := map[string]*dataFile{}
+	tr := tar.NewReader(reader)
+	var err error
+	for {
+		var header *tar.Header
+		if header, err = tr.Next(); err != nil {
+			break
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		var file *dataFile
+		if file, err = readDataFile(tr, header.Size, keep); err != nil {
+			break
+		}
+		files[stripVolumeDir(header.Name)] = file
+	}
+	reader.Close()
+
+	if copyErr := <-copied; copyErr != nil && copyErr != io.ErrClosedPipe {
+		return nil, fmt.Errorf("The marmots could not read %s in %s: %v", dir, containerName, copyErr)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return files, nil
+}
+
+// hostFiles hashes every file under dir on the host.
+func hostFiles(dir string, keep bool) (map[string]*dataFile, error) {
+	scanned, err := scanHostDir(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*dataFile{}
+	for name, info := range scanned {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		file, err := readDataFile(f, info.Size, keep)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = file
+	}
+	return files, nil
+}
+
+func readDataFile(reader io.Reader, size int64, keep bool) (*dataFile, error) {
+	keep = keep && size <= maxContentDiff
+	hash := sha256.New()
+	var contents bytes.Buffer
+	writer := io.Writer(hash)
+	if keep {
+		writer = io.MultiWriter(hash, &contents)
+	}
+	n, err := io.Copy(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &dataFile{Size: n, Hash: hex.EncodeToString(hash.Sum(nil))}
+	if keep {
+		file.Contents = contents.Bytes()
+	}
+	return file, nil
+}
+
+func hashFile(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sortedNames(files map[string]hostFile) []string {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
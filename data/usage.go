@@ -0,0 +1,369 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// DataUsage is the space the volumes of one data container, or the
+// named data volumes of one service or chain, take up. Owner is
+// service or chain, or empty when nothing uses the data.
+type DataUsage struct {
+	Name    string
+	Number  int
+	Owner   string
+	Volumes bool
+	Files   int
+	Size    int64
+}
+
+// DiskUsage reports the volume size of every data container and of the
+// named data volumes of every service and chain, along with the service
+// or chain it belongs to, largest first.
+func DiskUsage(do *definitions.Do) error {
+	usages, err := dataUsages()
+	if err != nil {
+		return err
+	}
+	if len(usages) == 0 {
+		logger.Println("There are no data containers or data volumes.")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	var total int64
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tOWNER\tKIND\tFILES\tSIZE")
+	names := []string{}
+	for _, u := range usages {
+		owner := u.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		kind := "container"
+		if u.Volumes {
+			kind = "volumes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", u.Name, owner, kind, u.Files, humanSize(u.Size))
+		names = append(names, u.Name)
+		total += u.Size
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t\t%s\n", humanSize(total))
+	w.Flush()
+	logger.Printf("%s", buf.String())
+
+	do.Result = strings.Join(names, "\n")
+	return nil
+}
+
+// PruneData removes the data containers and named data volumes which
+// belong to no service or chain (neither a definition file nor a
+// container of that name), and the folders in ~/.eris/data which have
+// no data container, data volumes or definition of the same name. It
+// lists what it will remove and asks first unless do.Force is given.
+func PruneData(do *definitions.Do) error {
+	usages, err := dataUsages()
+	if err != nil {
+		return err
+	}
+	orphans := []*DataUsage{}
+	for _, u := range usages {
+		if u.Owner == "" {
+			orphans = append(orphans, u)
+		}
+	}
+	dirs, err := orphanHostDirs(DataContainersPath, dataNames(usages))
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 && len(dirs) == 0 {
+		logger.Println("Nothing to prune.")
+		return nil
+	}
+
+	var freed int64
+	for _, u := range orphans {
+		if u.Volumes {
+			logger.Printf("Data volumes =>\t\t\t%s (%s)\n", u.Name, humanSize(u.Size))
+		} else {
+			logger.Printf("Data container =>\t\t%s (%s)\n", u.Name, humanSize(u.Size))
+		}
+		freed += u.Size
+	}
+	for _, dir := range sortedDirs(dirs) {
+		logger.Printf("Host folder =>\t\t\t%s (%s)\n", filepath.Join(DataContainersPath, dir), humanSize(dirs[dir]))
+		freed += dirs[dir]
+	}
+
+	if !do.Force {
+		var input string
+		fmt.Printf("Remove these to free %s? (y/N): ", humanSize(freed))
+		fmt.Scanln(&input)
+		if input != "y" && input != "Y" && input != "yes" && input != "Yes" {
+			logger.Println("Nothing removed.")
+			return nil
+		}
+	}
+
+	removed := []string{}
+	for _, u := range orphans {
+		if u.Volumes {
+			logger.Infof("Removing data volumes =>\t%s\n", u.Name)
+			if err := perform.DockerRemoveDataVolumes(util.DataContainersName(u.Name, u.Number)); err != nil {
+				return err
+			}
+			removed = append(removed, u.Name)
+			continue
+		}
+		logger.Infof("Removing data container =>\t%s\n", u.Name)
+		err := util.DockerClient.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            util.DataContainersName(u.Name, u.Number),
+			RemoveVolumes: true,
+		})
+		if err != nil {
+			return fmt.Errorf("The marmots could not remove the data container %s: %v", u.Name, err)
+		}
+		removed = append(removed, u.Name)
+	}
+	for _, dir := range sortedDirs(dirs) {
+		logger.Infof("Removing host folder =>\t\t%s\n", dir)
+		if err := os.RemoveAll(filepath.Join(DataContainersPath, dir)); err != nil {
+			return err
+		}
+		removed = append(removed, filepath.Join(DataContainersPath, dir))
+	}
+
+	logger.Printf("Pruned =>\t\t\t%d data containers or volumes and %d host folders (%s)\n", len(orphans), len(dirs), humanSize(freed))
+	do.Result = strings.Join(removed, "\n")
+	return nil
+}
+
+func dataUsages() ([]*DataUsage, error) {
+	// every eris container, running or not, by name
+	others := map[string]bool{}
+	for _, c := range append(util.ServiceContainers(true), util.ChainContainers(true)...) {
+		others[c.FullName] = true
+	}
+
+	usages := []*DataUsage{}
+	for _, c := range util.DataContainers() {
+		u := &DataUsage{Name: c.ShortName, Number: c.Number, Owner: dataOwnerKind(c, others)}
+
+		volumes, err := containerVolumes(c.FullName)
+		if err != nil {
+			return nil, err
+		}
+		for _, vol := range volumes {
+			files, size, err := volumeSize(c.FullName, vol)
+			if err != nil {
+				return nil, err
+			}
+			u.Files += files
+			u.Size += size
+		}
+		usages = append(usages, u)
+	}
+
+	// named volumes are listed by the Docker API from 1.9 on
+	owners, err := util.DataVolumeOwners()
+	if err != nil {
+		logger.Debugf("Could not list volumes =>\t%v\n", err)
+	}
+	for _, c := range owners {
+		u := &DataUsage{Name: c.ShortName, Number: c.Number, Owner: dataOwnerKind(c, others), Volumes: true}
+		if u.Files, u.Size, err = dataVolumesSize(c); err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+	}
+
+	sort.Sort(bySize(usages))
+	return usages, nil
+}
+
+// dataOwnerKind tells whether the data container c (or the named
+// volumes named after it) belongs to a service or a chain, by the
+// containers in others or else by the definition files.
+func dataOwnerKind(c *util.ContainerName, others map[string]bool) string {
+	switch {
+	case others[util.DataContainerToService(c.FullName)]:
+		return "service"
+	case others[util.DataContainerToChain(c.FullName)]:
+		return "chain"
+	case util.GetFileByNameAndType("services", c.ShortName) != "":
+		return "service"
+	case util.GetFileByNameAndType("chains", c.ShortName) != "":
+		return "chain"
+	}
+	return ""
+}
+
+// dataVolumesSize adds up the files in the named data volumes named
+// after the data container c, mounted for the moment in a holder.
+func dataVolumesSize(c *util.ContainerName) (int, int64, error) {
+	volumes, err := util.DataVolumes(c.FullName)
+	if err != nil {
+		return 0, 0, err
+	}
+	binds := []string{}
+	for _, volume := range volumes {
+		binds = append(binds, volume+":/eris_volumes/"+volume+":ro")
+	}
+
+	holder := util.VolumesHolderName(c.ShortName, c.Number) + "_du"
+	perform.DockerReleaseVolumes(holder) // left behind by a crash
+	if err := perform.DockerHoldVolumes(holder, "", binds); err != nil {
+		return 0, 0, err
+	}
+	defer perform.DockerReleaseVolumes(holder)
+
+	var files int
+	var size int64
+	for _, volume := range volumes {
+		f, s, err := volumeSize(holder, "/eris_volumes/"+volume)
+		if err != nil {
+			return 0, 0, err
+		}
+		files += f
+		size += s
+	}
+	return files, size, nil
+}
+
+// dataNames are the names which have data: a data container, named
+// data volumes, or a service or chain definition.
+func dataNames(usages []*DataUsage) map[string]bool {
+	names := map[string]bool{}
+	for _, u := range usages {
+		names[u.Name] = true
+	}
+	for _, typ := range []string{"services", "chains"} {
+		for _, file := range util.GetGlobalLevelConfigFilesByType(typ, false) {
+			names[strings.Split(filepath.Base(file), ".")[0]] = true
+		}
+	}
+	return names
+}
+
+// volumeSize adds up the regular files under dir in a container.
+func volumeSize(containerName, dir string) (int, int64, error) {
+	reader, writer := io.Pipe()
+	copied := make(chan error, 1)
+	go func() {
+		err := util.DockerClient.CopyFromContainer(docker.CopyFromContainerOptions{
+			OutputStream: writer,
+			Container:    containerName,
+			Resource:     dir,
+		})
+		writer.CloseWithError(err)
+		copied <- err
+	}()
+
+	var files int
+	var size int64
+	tr := tar.NewReader(reader)
+	var err error
+	for {
+		var header *tar.Header
+		if header, err = tr.Next(); err != nil {
+			break
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			files++
+			size += header.Size
+		}
+	}
+	reader.Close()
+
+	if copyErr := <-copied; copyErr != nil && copyErr != io.ErrClosedPipe {
+		return 0, 0, fmt.Errorf("The marmots could not read %s in %s: %v", dir, containerName, copyErr)
+	}
+	if err != io.EOF {
+		return 0, 0, err
+	}
+	return files, size, nil
+}
+
+// orphanHostDirs finds the folders under root which are not in names,
+// with their sizes.
+func orphanHostDirs(root string, names map[string]bool) (map[string]int64, error) {
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]int64{}, nil
+		}
+		return nil, err
+	}
+
+	dirs := map[string]int64{}
+	for _, info := range infos {
+		if !info.IsDir() || names[info.Name()] {
+			continue
+		}
+		var size int64
+		err := filepath.Walk(filepath.Join(root, info.Name()), func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		dirs[info.Name()] = size
+	}
+	return dirs, nil
+}
+
+func sortedDirs(dirs map[string]int64) []string {
+	names := []string{}
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// largest first, then by name
+type bySize []*DataUsage
+
+func (s bySize) Len() int      { return len(s) }
+func (s bySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySize) Less(i, j int) bool {
+	if s[i].Size != s[j].Size {
+		return s[i].Size > s[j].Size
+	}
+	return s[i].Name < s[j].Name
+}
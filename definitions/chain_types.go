@@ -0,0 +1,34 @@
+package definitions
+
+// ChainType describes a blockchain client the marmots know how to run:
+// which image to use, how to drive it, and where it keeps its files.
+type ChainType struct {
+	// name chain definitions refer to with chain_type
+	Name string `mapstructure:"name" json:"name" yaml:"name" toml:"name"`
+	// docker image the chain's containers run
+	Image string `mapstructure:"image" json:"image" yaml:"image" toml:"image"`
+
+	// commands run in the container to create, fetch, run,
+	// and run with the api server turned on
+	NewCmd     string `mapstructure:"new_cmd" json:"new_cmd" yaml:"new_cmd" toml:"new_cmd"`
+	InstallCmd string `mapstructure:"install_cmd" json:"install_cmd" yaml:"install_cmd" toml:"install_cmd"`
+	StartCmd   string `mapstructure:"start_cmd" json:"start_cmd" yaml:"start_cmd" toml:"start_cmd"`
+	APICmd     string `mapstructure:"api_cmd" json:"api_cmd" yaml:"api_cmd" toml:"api_cmd"`
+
+	// port of the tendermint style rpc server (e.g. 46657/tcp);
+	// leave empty when the client does not speak it
+	RPCPort string `mapstructure:"rpc_port" json:"rpc_port" yaml:"rpc_port" toml:"rpc_port"`
+	// ports published by default
+	Ports []string `mapstructure:"ports" json:"ports" yaml:"ports" toml:"ports"`
+
+	// directory (relative to the data container's root) holding
+	// a directory per chain, and the names of the genesis and main
+	// config files within a chain's directory
+	ChainsDir   string `mapstructure:"chains_dir" json:"chains_dir" yaml:"chains_dir" toml:"chains_dir"`
+	GenesisFile string `mapstructure:"genesis_file" json:"genesis_file" yaml:"genesis_file" toml:"genesis_file"`
+	ConfigFile  string `mapstructure:"config_file" json:"config_file" yaml:"config_file" toml:"config_file"`
+}
+
+func BlankChainType() *ChainType {
+	return &ChainType{}
+}
@@ -7,6 +7,14 @@ type Chain struct {
 	ChainID string `mapstructure:"chain_id" json:"chain_id" yaml:"chain_id" toml:"chain_id"`
 	// type of the chain
 	ChainType string `mapstructure:"chain_type" json:"chain_type" yaml:"chain_type" toml:"chain_type"`
+	// version (image tag) or sha256:<digest> of the node image
+	// the chain is pinned to
+	ErisDBVersion string `mapstructure:"erisdb_version" json:"erisdb_version,omitempty" yaml:"erisdb_version,omitempty" toml:"erisdb_version,omitempty"`
+	// ephemeral chains (throwaway chains) are removed by eris chains gc
+	// once ttl (e.g. 24h) has passed since created (RFC3339)
+	Ephemeral bool   `mapstructure:"ephemeral" json:"ephemeral,omitempty" yaml:"ephemeral,omitempty" toml:"ephemeral,omitempty"`
+	Created   string `mapstructure:"created" json:"created,omitempty" yaml:"created,omitempty" toml:"created,omitempty"`
+	TTL       string `mapstructure:"ttl" json:"ttl,omitempty" yaml:"ttl,omitempty" toml:"ttl,omitempty"`
 
 	// same fields as in the Service Struct/Service Specification
 	Service    *Service    `json:"service,omitempty" yaml:"service,omitempty" toml:"service,omitempty"`
@@ -8,6 +8,7 @@ type Do struct {
 	Pull          bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	SkipPull      bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	SkipImages    bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	SkipVerify    bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Quiet         bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	All           bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Follow        bool     `mapstructure:"," json:"," yaml:"," toml:","`
@@ -17,21 +18,37 @@ type Do struct {
 	RmHF          bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Verbose       bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Debug         bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Watch         bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	DryRun        bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Content       bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Recursive     bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Test          bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Lines         int      `mapstructure:"," json:"," yaml:"," toml:","`
+	Height        int      `mapstructure:"," json:"," yaml:"," toml:","`
+	Size          int      `mapstructure:"," json:"," yaml:"," toml:","`
 	Timeout       uint     `mapstructure:"," json:"," yaml:"," toml:","`
 	Type          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Task          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Tail          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ChainName     string   `mapstructure:"," json:"," yaml:"," toml:","`
+	ChainType     string   `mapstructure:"," json:"," yaml:"," toml:","`
 	GenesisFile   string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ConfigFile    string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ChainID       string   `mapstructure:"," json:"," yaml:"," toml:","`
 	MachineName   string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Name          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Path          string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Source        string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Destination   string   `mapstructure:"," json:"," yaml:"," toml:","`
 	NewName       string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Moniker       string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Key           string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Tag           string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Version       string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ResultFormt   string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ServicesSlice []string `mapstructure:"," json:"," yaml:"," toml:","`
+	Seeds         []string `mapstructure:"," json:"," yaml:"," toml:","`
+	Ignore        []string `mapstructure:"," json:"," yaml:"," toml:","`
 
 	// Generalized string slice
 	Args []string `mapstructure:"," json:"," yaml:"," toml:","`
@@ -7,6 +7,10 @@ type Service struct {
 	Image string `json:"image,omitempty" yaml:"image,omitempty" toml:"image,omitempty"`
 	// whether eris should automagically handle a data container for this service
 	AutoData bool `json:"data_container" yaml:"data_container" toml:"data_container"`
+	// whether that data lives in named docker volumes rather than in a data container
+	DataVolumes bool `mapstructure:"data_volumes" json:"data_volumes,omitempty" yaml:"data_volumes,omitempty" toml:"data_volumes,omitempty"`
+	// where those named volumes are mounted; eris data migrate records the paths of the data container
+	DataPaths []string `mapstructure:"data_paths" json:"data_paths,omitempty" yaml:"data_paths,omitempty" toml:"data_paths,omitempty"`
 	// maps directly to docker cmd
 	Command string `json:"command,omitempty" yaml:"command,omitempty" toml:"command,omitempty"`
 	// maps directly to docker links
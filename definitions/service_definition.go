@@ -10,6 +10,11 @@ type ServiceDefinition struct {
 	// a chain which must be started prior to this service starting. can take a `$chain` string
 	// which would then be passed in via a command line flag
 	Chain string `json:"chain,omitempty" yaml:"chain,omitempty" toml:"chain,omitempty"`
+	// set on services graduated from a chain so they can be demoted
+	// back into that chain: its name, type and pinned node version
+	ChainName     string `mapstructure:"chain_name" json:"chain_name,omitempty" yaml:"chain_name,omitempty" toml:"chain_name,omitempty"`
+	ChainType     string `mapstructure:"chain_type" json:"chain_type,omitempty" yaml:"chain_type,omitempty" toml:"chain_type,omitempty"`
+	ErisDBVersion string `mapstructure:"erisdb_version" json:"erisdb_version,omitempty" yaml:"erisdb_version,omitempty" toml:"erisdb_version,omitempty"`
 
 	Service    *Service    `json:"service" yaml:"service" toml:"service"`
 	Maintainer *Maintainer `json:"maintainer,omitempty" yaml:"maintainer,omitempty" toml:"maintainer,omitempty"`
@@ -11,6 +11,7 @@ import (
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
 	"github.com/eris-ltd/eris-cli/definitions"
 	ini "github.com/eris-ltd/eris-cli/initialize"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/util"
 )
 
@@ -85,6 +86,60 @@ func TestGetFiles(t *testing.T) {
 	}
 }
 
+func TestImportDirEscape(t *testing.T) {
+	node := ipfs.NewFake()
+	defer func(n ipfs.IPFS) { ipfs.Node = n }(ipfs.Node)
+	ipfs.Node = &escapingNode{Fake: node}
+
+	src, err := ioutil.TempDir("", "eris_files_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(src)
+	ioutil.WriteFile(path.Join(src, "index.html"), []byte(content), 0644)
+	root, err := node.AddDir(src, nil)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	ipfs.Node.(*escapingNode).root = root
+
+	parent, err := ioutil.TempDir("", "eris_files_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(parent)
+	dir := path.Join(parent, "site")
+
+	if err := importDir(root, dir); err == nil {
+		logger.Errorf("FAILURE: expected an error getting a folder with a link out of it.\n")
+		t.Fail()
+	}
+	for _, name := range []string{"escaped.html", path.Join("site", "index.html")} {
+		if _, err := os.Stat(path.Join(parent, name)); !os.IsNotExist(err) {
+			logger.Errorf("FAILURE: %s was written from a folder with a link out of it.\n", name)
+			t.Fail()
+		}
+	}
+}
+
+// escapingNode lists one more link under root, named to leave the
+// folder it is got into.
+type escapingNode struct {
+	*ipfs.Fake
+	root string
+}
+
+func (n *escapingNode) Ls(hash string) ([]ipfs.Link, error) {
+	links, err := n.Fake.Ls(hash)
+	if err != nil || hash != n.root || len(links) == 0 {
+		return links, err
+	}
+	return append(links, ipfs.Link{Name: "../escaped.html", Hash: links[0].Hash, Type: ipfs.File}), nil
+}
+
 func testsInit() error {
 	var err error
 	// TODO: make a reader/pipe so we can see what is written from tests.
@@ -1,10 +1,13 @@
 package files
 
 import (
-	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/services"
-	"github.com/eris-ltd/eris-cli/util"
 )
 
 func GetFiles(do *definitions.Do) error {
@@ -15,8 +18,13 @@ func GetFiles(do *definitions.Do) error {
 		return err
 	}
 	logger.Infoln("IPFS is running.")
-	logger.Debugf("Gonna Import a file =>\t\t%s:%v\n", do.Name, do.Path)
-	err = importFile(do.Name, do.Path)
+	if do.Recursive {
+		logger.Debugf("Gonna Import a folder =>\t\t%s:%v\n", do.Name, do.Path)
+		err = importDir(do.Name, do.Path)
+	} else {
+		logger.Debugf("Gonna Import a file =>\t\t%s:%v\n", do.Name, do.Path)
+		err = importFile(do.Name, do.Path)
+	}
 	if err != nil {
 		return err
 	}
@@ -33,8 +41,13 @@ func PutFiles(do *definitions.Do) error {
 		return err
 	}
 	logger.Infoln("IPFS is running.")
-	logger.Debugf("Gonna Add a file =>\t\t%s:%v\n", do.Name, do.Path)
-	hash, err = exportFile(do.Name)
+	if do.Recursive {
+		logger.Debugf("Gonna Add a folder =>\t\t%s:%v\n", do.Name, do.Path)
+		hash, err = exportDir(do.Name)
+	} else {
+		logger.Debugf("Gonna Add a file =>\t\t%s:%v\n", do.Name, do.Path)
+		hash, err = exportFile(do.Name)
+	}
 	if err != nil {
 		return err
 	}
@@ -114,89 +127,89 @@ func ListPinned(do *definitions.Do) error {
 	return nil
 }
 func importFile(hash, fileName string) error {
-	var err error
-	if logger.Level > 0 {
-		err = util.GetFromIPFS(hash, fileName, logger.Writer)
-	} else {
-		err = util.GetFromIPFS(hash, fileName, bytes.NewBuffer([]byte{}))
-	}
+	return ipfs.Node.Get(hash, fileName)
+}
 
+// importDir rebuilds the IPFS directory hash in dir, one file at a
+// time. A hash which is a single file is saved in dir under its hash.
+func importDir(hash, dir string) error {
+	entries, err := ipfs.Walk(ipfs.Node, hash)
 	if err != nil {
 		return err
 	}
+	// every name is checked before anything is written
+	fileNames := []string{}
+	for _, entry := range entries {
+		name := entry.Path
+		if name == "" {
+			name = entry.Hash
+		}
+		fileName, err := ipfs.LocalPath(dir, name)
+		if err != nil {
+			return err
+		}
+		fileNames = append(fileNames, fileName)
+	}
+
+	for i, entry := range entries {
+		fileName := fileNames[i]
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+
+		logger.Printf("Getting =>\t\t\t[%d/%d] %s\n", i+1, len(entries), fileName)
+		if err := ipfs.Node.Get(entry.Hash, fileName); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func exportFile(fileName string) (string, error) {
-	var hash string
-	var err error
-
-	if logger.Level > 0 {
-		hash, err = util.SendToIPFS(fileName, logger.Writer)
-	} else {
-		hash, err = util.SendToIPFS(fileName, bytes.NewBuffer([]byte{}))
-	}
-	if err != nil {
-		return "", err
-	}
+	return ipfs.Node.Add(fileName)
+}
 
-	return hash, nil
+// exportDir adds the folder dir to IPFS and returns its hash.
+func exportDir(dir string) (string, error) {
+	return ipfs.Node.AddDir(dir, func(entry ipfs.Entry) {
+		if entry.Path != "" {
+			logger.Printf("Added =>\t\t\t%s %s\n", entry.Hash, entry.Path)
+		}
+	})
 }
 
 func pinFile(fileHash string) (string, error) {
-	var hash string
-	var err error
-
-	if logger.Level > 0 {
-		hash, err = util.PinToIPFS(fileHash, logger.Writer)
-	} else {
-		hash, err = util.PinToIPFS(fileHash, bytes.NewBuffer([]byte{}))
-	}
+	pinned, err := ipfs.Node.PinAdd(fileHash)
 	if err != nil {
 		return "", err
 	}
-	return hash, nil
+	return strings.Join(pinned, "\n"), nil
 }
 
 func catFile(fileHash string) (string, error) {
-	var hash string
-	var err error
-	//CatFrom may have to contents here
-	if logger.Level > 0 {
-		hash, err = util.CatFromIPFS(fileHash, logger.Writer)
-	} else {
-		hash, err = util.CatFromIPFS(fileHash, bytes.NewBuffer([]byte{}))
-	}
+	contents, err := ipfs.Node.Cat(fileHash)
 	if err != nil {
 		return "", err
 	}
-	return hash, nil
+	return string(contents), nil
 }
 
 func listFile(objectHash string) (string, error) {
-	var hash string
-	var err error
-	if logger.Level > 0 {
-		hash, err = util.ListFromIPFS(objectHash, logger.Writer)
-	} else {
-		hash, err = util.ListFromIPFS(objectHash, bytes.NewBuffer([]byte{}))
-	}
+	links, err := ipfs.Node.Ls(objectHash)
 	if err != nil {
 		return "", err
 	}
-	return hash, nil
+	res := make([]string, len(links))
+	for i, link := range links {
+		res[i] = link.Hash + " " + link.Name
+	}
+	return strings.Join(res, "\n"), nil
 }
 
 func listPinned() (string, error) {
-	var hash string
-	var err error
-	if logger.Level > 0 {
-		hash, err = util.ListPinnedFromIPFS(logger.Writer)
-	} else {
-		hash, err = util.ListPinnedFromIPFS(bytes.NewBuffer([]byte{}))
-	}
+	pinned, err := ipfs.Node.PinLs()
 	if err != nil {
 		return "", err
 	}
-	return hash, nil
+	return strings.Join(pinned, "\n"), nil
 }
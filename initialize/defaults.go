@@ -58,7 +58,9 @@ requires = [""]
 `
 }
 
-func defChainConfig() string {
+// DefChainConfig is the config.toml of the default chain. Nodes set up
+// by eris chains join start from it too.
+func DefChainConfig() string {
   return `
 # This is a TOML config file.
 # For more information, see https://github.com/toml-lang/toml
@@ -37,7 +37,7 @@ func dropDefaults() error {
 
 func dropChainDefaults() error {
 	defChainDir := filepath.Join(common.BlockchainsPath, "config", "default")
-	if err := writeDefaultFile(defChainDir, "config.toml", defChainConfig); err != nil {
+	if err := writeDefaultFile(defChainDir, "config.toml", DefChainConfig); err != nil {
 		return fmt.Errorf("Cannot add default config.toml: %s.\n", err)
 	}
 	if err := writeDefaultFile(defChainDir, "genesis.json", defChainGen); err != nil {
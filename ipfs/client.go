@@ -0,0 +1,396 @@
+package ipfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/util"
+)
+
+// what the gateway answers with when it gives up on a hash
+const resolveTimeout = "Path Resolve error: context deadline exceeded"
+
+// Client talks to an IPFS node over its HTTP API and gateway.
+type Client struct {
+	API     string // http://host:5001/api/v0/, from the eris config when empty
+	Gateway string // http://host:8080/ipfs/, likewise
+}
+
+// Error is what the IPFS API answers with when a command fails.
+type Error struct {
+	Command string
+	Message string
+	Code    int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("IPFS error on %s: %s", e.Command, e.Message)
+}
+
+func (c *Client) Add(fileName string) (string, error) {
+	logger.Debugf("Adding file to IPFS =>\t\t%s\n", fileName)
+	return c.addFile(fileName, url.Values{})
+}
+
+func (c *Client) Hash(fileName string) (string, error) {
+	logger.Debugf("Hashing file with IPFS =>\t%s\n", fileName)
+	return c.addFile(fileName, url.Values{"only-hash": {"true"}})
+}
+
+func (c *Client) AddDir(dir string, added func(Entry)) (string, error) {
+	logger.Debugf("Adding folder to IPFS =>\t%s\n", dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder.", dir)
+	}
+
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeDirForm(form, dir))
+	}()
+
+	args := url.Values{"recursive": {"true"}, "wrap-with-directory": {"true"}}
+	body, err := c.do("add", args, form.FormDataContentType(), reader)
+	reader.Close()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	// IPFS answers with one object per line, the wrapping directory last
+	var root string
+	dec := json.NewDecoder(body)
+	for {
+		var object struct {
+			Name, Hash string
+		}
+		if err := dec.Decode(&object); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("The marmots could not understand IPFS's response to add: %v", err)
+		}
+		if object.Hash == "" {
+			continue
+		}
+		if added != nil {
+			added(Entry{Hash: object.Hash, Path: object.Name})
+		}
+		if object.Name == "" {
+			root = object.Hash
+		}
+	}
+	if root == "" {
+		return "", fmt.Errorf("IPFS returned no hash for %s.", dir)
+	}
+	return root, nil
+}
+
+// Get downloads through the gateway, retrying and resuming as
+// util.DownloadVerified does, and only replaces fileName once the
+// download matches hash.
+func (c *Client) Get(hash, fileName string) error {
+	logger.Debugf("Getting file from IPFS =>\t%s:%s\n", hash, fileName)
+	var w io.Writer = ioutil.Discard
+	if logger.Level > 0 {
+		w = logger.Writer
+	}
+	return util.DownloadVerified(c.gateway()+hash, fileName, func(tempName string) error {
+		return c.verify(hash, tempName)
+	}, w)
+}
+
+func (c *Client) Cat(hash string) ([]byte, error) {
+	logger.Debugf("Catting file from IPFS =>\t%s\n", hash)
+	return c.call("cat", url.Values{"arg": {hash}})
+}
+
+func (c *Client) Ls(hash string) ([]Link, error) {
+	logger.Debugf("Listing object from IPFS =>\t%s\n", hash)
+	raw, err := c.call("ls", url.Values{"arg": {hash}})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Objects []struct {
+			Hash  string
+			Links []Link
+		}
+	}
+	if err := decode("ls", raw, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Objects) == 0 {
+		return nil, fmt.Errorf("IPFS returned no object for %s.", hash)
+	}
+	return out.Objects[0].Links, nil
+}
+
+func (c *Client) PinAdd(hash string) ([]string, error) {
+	logger.Debugf("Pinning to IPFS =>\t\t%s\n", hash)
+	return c.pin("pin/add", hash)
+}
+
+func (c *Client) PinRm(hash string) ([]string, error) {
+	logger.Debugf("Unpinning from IPFS =>\t\t%s\n", hash)
+	return c.pin("pin/rm", hash)
+}
+
+func (c *Client) PinLs() ([]string, error) {
+	logger.Debugln("Listing files pinned to IPFS.")
+	raw, err := c.call("pin/ls", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Keys map[string]struct {
+			Type  string
+			Count int
+		}
+	}
+	if err := decode("pin/ls", raw, &out); err != nil {
+		return nil, err
+	}
+	pins := []string{}
+	for hash := range out.Keys {
+		pins = append(pins, hash)
+	}
+	return pins, nil
+}
+
+func (c *Client) NamePublish(hash string) (string, error) {
+	logger.Debugf("Publishing to IPFS =>\t\t%s\n", hash)
+	raw, err := c.call("name/publish", url.Values{"arg": {hash}})
+	if err != nil {
+		return "", err
+	}
+
+	var out struct {
+		Name, Value string
+	}
+	if err := decode("name/publish", raw, &out); err != nil {
+		return "", err
+	}
+	return out.Name, nil
+}
+
+func (c *Client) NameResolve(name string) (string, error) {
+	logger.Debugf("Resolving IPFS name =>\t\t%s\n", name)
+	raw, err := c.call("name/resolve", url.Values{"arg": {name}})
+	if err != nil {
+		return "", err
+	}
+
+	var out struct {
+		Path string
+	}
+	if err := decode("name/resolve", raw, &out); err != nil {
+		return "", err
+	}
+	return out.Path, nil
+}
+
+func (c *Client) pin(command, hash string) ([]string, error) {
+	raw, err := c.call(command, url.Values{"arg": {hash}})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Pinned []string
+		Pins   []string
+	}
+	if err := decode(command, raw, &out); err != nil {
+		return nil, err
+	}
+	return append(out.Pinned, out.Pins...), nil
+}
+
+func (c *Client) addFile(fileName string, args url.Values) (string, error) {
+	input, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer input.Close()
+
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("file", filepath.Base(fileName))
+		if err == nil {
+			_, err = io.Copy(part, input)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	body, err := c.do("add", args, form.FormDataContentType(), reader)
+	reader.Close()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	var added struct {
+		Name, Hash string
+	}
+	if err := decode("add", raw, &added); err != nil {
+		return "", err
+	}
+	if added.Hash == "" {
+		return "", fmt.Errorf("IPFS returned no hash for %s.", fileName)
+	}
+	return added.Hash, nil
+}
+
+// call runs an API command which takes no body and returns what it
+// answered with.
+func (c *Client) call(command string, args url.Values) ([]byte, error) {
+	body, err := c.do(command, args, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// do POSTs an API command, turning whatever IPFS says went wrong into
+// an error.
+func (c *Client) do(command string, args url.Values, contentType string, body io.Reader) (io.ReadCloser, error) {
+	address := c.api() + command
+	if len(args) > 0 {
+		address += "?" + args.Encode()
+	}
+	request, err := http.NewRequest("POST", address, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < http.StatusBadRequest {
+		return response.Body, nil
+	}
+	defer response.Body.Close()
+
+	raw, _ := ioutil.ReadAll(response.Body)
+	if strings.TrimSpace(string(raw)) == resolveTimeout {
+		return nil, timeoutError()
+	}
+	e := &Error{Command: command, Code: response.StatusCode}
+	var msg struct {
+		Message string
+		Code    int
+	}
+	if err := json.Unmarshal(raw, &msg); err == nil && msg.Message != "" {
+		e.Message = msg.Message
+	} else if text := strings.TrimSpace(string(raw)); text != "" {
+		e.Message = text
+	} else {
+		e.Message = response.Status
+	}
+	return nil, e
+}
+
+func (c *Client) api() string {
+	if c.API != "" {
+		return c.API
+	}
+	return host() + ":5001/api/v0/"
+}
+
+func (c *Client) gateway() string {
+	if c.Gateway != "" {
+		return c.Gateway
+	}
+	return host() + ":8080/ipfs/"
+}
+
+// host is where the IPFS node listens: the ipfs container from inside
+// another container, else ERIS_IPFS_HOST or the eris config's IpfsHost.
+func host() string {
+	if os.Getenv("ERIS_CLI_CONTAINER") == "true" {
+		return "http://ipfs"
+	}
+	if os.Getenv("ERIS_IPFS_HOST") != "" {
+		return os.Getenv("ERIS_IPFS_HOST")
+	}
+	return util.GetConfigValue("IpfsHost")
+}
+
+// writeDirForm writes the folders and regular files under dir as parts
+// named by their slash separated paths relative to dir.
+func writeDirForm(form *multipart.Writer, dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil || name == "." {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, url.QueryEscape(name)))
+		switch {
+		case info.IsDir():
+			header.Set("Content-Type", "application/x-directory")
+			_, err := form.CreatePart(header)
+			return err
+		case info.Mode().IsRegular():
+			header.Set("Content-Type", "application/octet-stream")
+			part, err := form.CreatePart(header)
+			if err != nil {
+				return err
+			}
+			input, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer input.Close()
+			_, err = io.Copy(part, input)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+func decode(command string, raw []byte, v interface{}) error {
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(v); err != nil {
+		return fmt.Errorf("The marmots could not understand IPFS's response to %s: %v", command, err)
+	}
+	return nil
+}
+
+func timeoutError() error {
+	return fmt.Errorf("A timeout occured while trying to reach IPFS. Run `eris files cache [hash], wait 5-10 seconds, then run `eris files [cmd] [hash]`")
+}
@@ -0,0 +1,221 @@
+package ipfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fake is an IPFS node held in memory, for tests. Its hashes look like
+// IPFS hashes but are not the ones a real node would give.
+type Fake struct {
+	// Self is the name the node publishes under.
+	Self string
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	pins    map[string]bool
+	names   map[string]string
+}
+
+type fakeObject struct {
+	Dir   bool
+	Data  []byte
+	Links []Link
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		Self:    "QmFakePeer",
+		objects: map[string]*fakeObject{},
+		pins:    map[string]bool{},
+		names:   map[string]string{},
+	}
+}
+
+func (f *Fake) Add(fileName string) (string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.put(&fakeObject{Data: data}), nil
+}
+
+func (f *Fake) Hash(fileName string) (string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	return fakeHash(&fakeObject{Data: data}), nil
+}
+
+func (f *Fake) AddDir(dir string, added func(Entry)) (string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder.", dir)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addDir(dir, "", added)
+}
+
+func (f *Fake) Get(hash, fileName string) error {
+	data, err := f.Cat(hash)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+func (f *Fake) Cat(hash string) ([]byte, error) {
+	object, err := f.object("cat", hash)
+	if err != nil {
+		return nil, err
+	}
+	if object.Dir {
+		return nil, &Error{Command: "cat", Message: "this dag node is a directory"}
+	}
+	return object.Data, nil
+}
+
+func (f *Fake) Ls(hash string) ([]Link, error) {
+	object, err := f.object("ls", hash)
+	if err != nil {
+		return nil, err
+	}
+	return object.Links, nil
+}
+
+func (f *Fake) PinAdd(hash string) ([]string, error) {
+	if _, err := f.object("pin/add", hash); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pins[hash] = true
+	return []string{hash}, nil
+}
+
+func (f *Fake) PinLs() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pins := []string{}
+	for hash := range f.pins {
+		pins = append(pins, hash)
+	}
+	sort.Strings(pins)
+	return pins, nil
+}
+
+func (f *Fake) PinRm(hash string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.pins[hash] {
+		return nil, &Error{Command: "pin/rm", Message: "not pinned"}
+	}
+	delete(f.pins, hash)
+	return []string{hash}, nil
+}
+
+func (f *Fake) NamePublish(hash string) (string, error) {
+	if _, err := f.object("name/publish", strings.TrimPrefix(hash, "/ipfs/")); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.names[f.Self] = "/ipfs/" + strings.TrimPrefix(hash, "/ipfs/")
+	return f.Self, nil
+}
+
+func (f *Fake) NameResolve(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path, ok := f.names[strings.TrimPrefix(name, "/ipns/")]
+	if !ok {
+		return "", &Error{Command: "name/resolve", Message: "could not resolve name"}
+	}
+	return path, nil
+}
+
+func (f *Fake) object(command, hash string) (*fakeObject, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[hash]
+	if !ok {
+		return nil, &Error{Command: command, Message: fmt.Sprintf("merkledag: not found: %s", hash)}
+	}
+	return object, nil
+}
+
+// addDir adds the tree under dir, reporting objects by their paths
+// under prefix.
+func (f *Fake) addDir(dir, prefix string, added func(Entry)) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	object := &fakeObject{Dir: true, Links: []Link{}}
+	for _, info := range infos {
+		name := info.Name()
+		if prefix != "" {
+			name = prefix + "/" + name
+		}
+
+		var hash string
+		typ := File
+		switch {
+		case info.IsDir():
+			if hash, err = f.addDir(filepath.Join(dir, info.Name()), name, added); err != nil {
+				return "", err
+			}
+			typ = Directory
+		case info.Mode().IsRegular():
+			data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+			if err != nil {
+				return "", err
+			}
+			hash = f.put(&fakeObject{Data: data})
+			if added != nil {
+				added(Entry{Hash: hash, Path: name})
+			}
+		default:
+			continue
+		}
+		object.Links = append(object.Links, Link{Name: info.Name(), Hash: hash, Size: uint64(info.Size()), Type: typ})
+	}
+
+	hash := f.put(object)
+	if added != nil {
+		added(Entry{Hash: hash, Path: prefix})
+	}
+	return hash, nil
+}
+
+func (f *Fake) put(object *fakeObject) string {
+	hash := fakeHash(object)
+	f.objects[hash] = object
+	return hash
+}
+
+func fakeHash(object *fakeObject) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\x00", object.Dir)
+	h.Write(object.Data)
+	for _, link := range object.Links {
+		fmt.Fprintf(h, "\x00%s\x00%s", link.Name, link.Hash)
+	}
+	return "Qm" + hex.EncodeToString(h.Sum(nil))[:44]
+}
@@ -0,0 +1,124 @@
+package ipfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// IPFS is what eris needs from an IPFS node. Client talks to a real
+// one over its HTTP API; Fake keeps everything in memory for tests.
+type IPFS interface {
+	// Add adds a file and returns its hash.
+	Add(fileName string) (string, error)
+	// AddDir adds a folder wrapped in one directory object and returns
+	// the folder's hash. added, if given, is called for each object as
+	// it is added, the folder itself (with an empty Path) last.
+	AddDir(dir string, added func(Entry)) (string, error)
+	// Hash returns the hash a file would be added under, without
+	// adding it.
+	Hash(fileName string) (string, error)
+	// Get saves the file hash to fileName.
+	Get(hash, fileName string) error
+	// Cat returns the contents of the file hash.
+	Cat(hash string) ([]byte, error)
+	// Ls returns the links of the object hash.
+	Ls(hash string) ([]Link, error)
+	// PinAdd caches hash on the node and returns what was pinned.
+	PinAdd(hash string) ([]string, error)
+	// PinLs returns the hashes pinned on the node.
+	PinLs() ([]string, error)
+	// PinRm uncaches hash and returns what was unpinned.
+	PinRm(hash string) ([]string, error)
+	// NamePublish publishes hash under the node's name and returns
+	// the name.
+	NamePublish(hash string) (string, error)
+	// NameResolve returns the path a name was published with.
+	NameResolve(name string) (string, error)
+}
+
+// Node is the IPFS node the rest of eris talks to. Tests swap in a Fake.
+var Node IPFS = &Client{}
+
+// Link is one link of an IPFS object. The links of a directory are
+// named; those of a file are its chunks and have no names.
+type Link struct {
+	Name string
+	Hash string
+	Size uint64
+	Type int
+}
+
+// the link types IPFS lists
+const (
+	Directory = 1
+	File      = 2
+)
+
+// Entry is a file in an IPFS directory, by its path relative to the
+// directory.
+type Entry struct {
+	Hash string
+	Path string
+}
+
+// LocalPath is where the entry at entryPath is saved under dir. The
+// path is made of link names another node chose, so one which is
+// absolute or climbs out of dir is refused.
+func LocalPath(dir, entryPath string) (string, error) {
+	name := filepath.FromSlash(entryPath)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(entryPath, "/") {
+		return "", fmt.Errorf("The marmots will not save %s: the path is absolute.", entryPath)
+	}
+	for _, part := range strings.Split(strings.Replace(entryPath, "\\", "/", -1), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("The marmots will not save %s: the path leaves the folder.", entryPath)
+		}
+	}
+
+	dir = filepath.Clean(dir)
+	fileName := filepath.Join(dir, name)
+	if fileName != dir && !strings.HasPrefix(fileName, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("The marmots will not save %s: the path leaves the folder.", entryPath)
+	}
+	return fileName, nil
+}
+
+// Walk lists every file under an IPFS object. An object which is a file
+// lists as itself, with an empty Path.
+func Walk(node IPFS, hash string) ([]Entry, error) {
+	return walk(node, hash, false)
+}
+
+func walk(node IPFS, hash string, dir bool) ([]Entry, error) {
+	links, err := node.Ls(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, link := range links {
+		if link.Name == "" {
+			continue
+		}
+		children := []Entry{{Hash: link.Hash}}
+		if link.Type != File {
+			if children, err = walk(node, link.Hash, link.Type == Directory); err != nil {
+				return nil, err
+			}
+		}
+		for _, child := range children {
+			if child.Path == "" {
+				child.Path = link.Name
+			} else {
+				child.Path = link.Name + "/" + child.Path
+			}
+			entries = append(entries, child)
+		}
+	}
+	// an empty directory has no files, but a file is one
+	if len(entries) == 0 && !dir {
+		entries = append(entries, Entry{Hash: hash})
+	}
+	return entries, nil
+}
@@ -0,0 +1,321 @@
+package ipfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eris-ltd/eris-cli/util"
+)
+
+// newStubIPFS answers the API commands the client uses like an IPFS
+// node holding a single file, QmFile, pinned and published.
+func newStubIPFS(parts map[string]string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/add", func(w http.ResponseWriter, r *http.Request) {
+		form, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := form.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name, _ := url.QueryUnescape(part.FileName())
+			parts[name] = part.Header.Get("Content-Type")
+			fmt.Fprintf(w, `{"Name":%q,"Hash":"Qm%s"}`+"\n", name, strings.Replace(name, "/", "-", -1))
+		}
+		if r.URL.Query().Get("wrap-with-directory") == "true" {
+			fmt.Fprintln(w, `{"Name":"","Hash":"QmRoot"}`)
+		}
+	})
+	mux.HandleFunc("/api/v0/cat", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("arg") != "QmFile" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, `{"Message":"merkledag: not found","Code":0}`)
+			return
+		}
+		fmt.Fprint(w, "test content\n")
+	})
+	mux.HandleFunc("/api/v0/ls", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Objects":[{"Hash":"QmRoot","Links":[{"Name":"file","Hash":"QmFile","Size":13,"Type":2}]}]}`)
+	})
+	mux.HandleFunc("/api/v0/pin/add", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Pinned":[%q]}`+"\n", r.URL.Query().Get("arg"))
+	})
+	mux.HandleFunc("/api/v0/pin/ls", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Keys":{"QmFile":{"Type":"recursive","Count":1}}}`)
+	})
+	mux.HandleFunc("/api/v0/name/publish", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Name":"QmPeer","Value":%q}`+"\n", r.URL.Query().Get("arg"))
+	})
+	mux.HandleFunc("/api/v0/name/resolve", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Path":"/ipfs/QmFile"}`)
+	})
+	mux.HandleFunc("/ipfs/", func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/ipfs/") {
+		case fileHash([]byte("test content\n")):
+			fmt.Fprint(w, "test content\n")
+		case helloHash:
+			// not what was asked for
+			fmt.Fprint(w, "test content\n")
+		default:
+			http.Error(w, resolveTimeout, http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+// well known hashes of an empty file and of "hello world\n"
+const (
+	emptyHash = "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"
+	helloHash = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+)
+
+func TestMain(m *testing.M) {
+	// fail fast rather than retrying downloads
+	util.GlobalConfig = &util.ErisCli{Config: &util.ErisConfig{DownloadRetries: 1, DownloadBackoff: "1ms"}}
+	os.Exit(m.Run())
+}
+
+func TestFileHash(t *testing.T) {
+	for data, hash := range map[string]string{"": emptyHash, "hello world\n": helloHash} {
+		if got := fileHash([]byte(data)); got != hash {
+			logger.Errorf("FAILURE: improper hash of %q. expected: %s\tgot: %s\n", data, hash, got)
+			t.Fail()
+		}
+		code, digest, err := decodeMultihash(hash)
+		if err != nil || code != sha2_256 || len(digest) != 32 {
+			logger.Errorf("FAILURE: could not decode %s: %x %x %v\n", hash, code, digest, err)
+			t.Fail()
+		}
+	}
+	if _, _, err := decodeMultihash("QmFile"); err == nil {
+		logger.Errorf("FAILURE: expected an error decoding QmFile.\n")
+		t.Fail()
+	}
+}
+
+func TestClient(t *testing.T) {
+	parts := map[string]string{}
+	server := httptest.NewServer(newStubIPFS(parts))
+	defer server.Close()
+	c := &Client{API: server.URL + "/api/v0/", Gateway: server.URL + "/ipfs/"}
+
+	dir, err := ioutil.TempDir("", "eris_ipfs_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "file")
+	ioutil.WriteFile(fileName, []byte("test content\n"), 0644)
+
+	if hash, err := c.Add(fileName); err != nil || hash != "Qmfile" {
+		logger.Errorf("FAILURE: improper hash on ADD. expected: %s\tgot: %s:%v\n", "Qmfile", hash, err)
+		t.Fail()
+	}
+
+	if err := c.Get(fileHash([]byte("test content\n")), filepath.Join(dir, "got")); err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if raw, _ := ioutil.ReadFile(filepath.Join(dir, "got")); string(raw) != "test content\n" {
+		logger.Errorf("FAILURE: improper contents on GET. expected: %s\tgot: %s\n", "test content", raw)
+		t.Fail()
+	}
+	if err := c.Get(emptyHash, filepath.Join(dir, "missing")); err == nil || !strings.Contains(err.Error(), "timeout") {
+		logger.Errorf("FAILURE: expected a timeout error on GET. got: %v\n", err)
+		t.Fail()
+	}
+
+	// a file which does not match its hash leaves the old one alone
+	ioutil.WriteFile(filepath.Join(dir, "hello"), []byte("old\n"), 0644)
+	if err := c.Get(helloHash, filepath.Join(dir, "hello")); err == nil || !strings.Contains(err.Error(), "does not match") {
+		logger.Errorf("FAILURE: expected a hash mismatch on GET. got: %v\n", err)
+		t.Fail()
+	}
+	if raw, _ := ioutil.ReadFile(filepath.Join(dir, "hello")); string(raw) != "old\n" {
+		logger.Errorf("FAILURE: GET replaced a file with a bad download. got: %s\n", raw)
+		t.Fail()
+	}
+
+	if raw, err := c.Cat("QmFile"); err != nil || string(raw) != "test content\n" {
+		logger.Errorf("FAILURE: improper contents on CAT. expected: %s\tgot: %s:%v\n", "test content", raw, err)
+		t.Fail()
+	}
+	// IPFS's error message is an error, not the file
+	_, err = c.Cat("QmMissing")
+	if e, ok := err.(*Error); !ok || e.Message != "merkledag: not found" {
+		logger.Errorf("FAILURE: expected an IPFS error on CAT. got: %v\n", err)
+		t.Fail()
+	}
+
+	if links, err := c.Ls("QmRoot"); err != nil || !reflect.DeepEqual(links, []Link{{"file", "QmFile", 13, File}}) {
+		logger.Errorf("FAILURE: improper links on LS. got: %v:%v\n", links, err)
+		t.Fail()
+	}
+	if pinned, err := c.PinAdd("QmFile"); err != nil || !reflect.DeepEqual(pinned, []string{"QmFile"}) {
+		logger.Errorf("FAILURE: improper pins on PIN ADD. got: %v:%v\n", pinned, err)
+		t.Fail()
+	}
+	if pinned, err := c.PinLs(); err != nil || !reflect.DeepEqual(pinned, []string{"QmFile"}) {
+		logger.Errorf("FAILURE: improper pins on PIN LS. got: %v:%v\n", pinned, err)
+		t.Fail()
+	}
+	if name, err := c.NamePublish("QmFile"); err != nil || name != "QmPeer" {
+		logger.Errorf("FAILURE: improper name on PUBLISH. expected: %s\tgot: %s:%v\n", "QmPeer", name, err)
+		t.Fail()
+	}
+	if path, err := c.NameResolve("QmPeer"); err != nil || path != "/ipfs/QmFile" {
+		logger.Errorf("FAILURE: improper path on RESOLVE. expected: %s\tgot: %s:%v\n", "/ipfs/QmFile", path, err)
+		t.Fail()
+	}
+}
+
+func TestClientAddDir(t *testing.T) {
+	parts := map[string]string{}
+	server := httptest.NewServer(newStubIPFS(parts))
+	defer server.Close()
+	c := &Client{API: server.URL + "/api/v0/"}
+
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	added := []Entry{}
+	root, err := c.AddDir(dir, func(entry Entry) {
+		added = append(added, entry)
+	})
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if root != "QmRoot" {
+		logger.Errorf("FAILURE: improper root on ADD. expected: %s\tgot: %s\n", "QmRoot", root)
+		t.Fail()
+	}
+
+	expected := map[string]string{
+		"empty":         "application/x-directory",
+		"index.html":    "application/octet-stream",
+		"js":            "application/x-directory",
+		"js/lib":        "application/x-directory",
+		"js/lib/app.js": "application/octet-stream",
+	}
+	if !reflect.DeepEqual(parts, expected) {
+		logger.Errorf("FAILURE: improper parts on ADD. expected: %v\tgot: %v\n", expected, parts)
+		t.Fail()
+	}
+	if len(added) != len(expected)+1 || added[len(added)-1].Path != "" {
+		logger.Errorf("FAILURE: expected every object and then the root to be reported. got: %v\n", added)
+		t.Fail()
+	}
+}
+
+func TestFake(t *testing.T) {
+	node := NewFake()
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	root, err := node.AddDir(dir, nil)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	entries, err := Walk(node, root)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	if expected := []string{"index.html", "js/lib/app.js"}; !reflect.DeepEqual(paths, expected) {
+		logger.Errorf("FAILURE: improper files on WALK. expected: %v\tgot: %v\n", expected, paths)
+		t.Fail()
+	}
+
+	// the same file hashes the same however it is added
+	fileName := filepath.Join(dir, "js", "lib", "app.js")
+	hash, _ := node.Hash(fileName)
+	if added, _ := node.Add(fileName); hash != added || hash != entries[1].Hash {
+		logger.Errorf("FAILURE: improper hashes. HASH: %s\tADD: %s\tWALK: %s\n", hash, added, entries[1].Hash)
+		t.Fail()
+	}
+	if raw, err := node.Cat(hash); err != nil || string(raw) != "var x;" {
+		logger.Errorf("FAILURE: improper contents on CAT. expected: %s\tgot: %s:%v\n", "var x;", raw, err)
+		t.Fail()
+	}
+	if _, err := node.Cat(root); err == nil {
+		logger.Errorf("FAILURE: expected an error on CAT of a directory.\n")
+		t.Fail()
+	}
+
+	node.PinAdd(root)
+	if pinned, _ := node.PinLs(); !reflect.DeepEqual(pinned, []string{root}) {
+		logger.Errorf("FAILURE: improper pins on PIN LS. expected: %v\tgot: %v\n", []string{root}, pinned)
+		t.Fail()
+	}
+	node.PinRm(root)
+	if pinned, _ := node.PinLs(); len(pinned) != 0 {
+		logger.Errorf("FAILURE: expected no pins after PIN RM. got: %v\n", pinned)
+		t.Fail()
+	}
+
+	name, err := node.NamePublish(root)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if path, err := node.NameResolve(name); err != nil || path != "/ipfs/"+root {
+		logger.Errorf("FAILURE: improper path on RESOLVE. expected: %s\tgot: %s:%v\n", "/ipfs/"+root, path, err)
+		t.Fail()
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "eris_ipfs")
+	for entryPath, expected := range map[string]string{
+		"index.html":     filepath.Join(dir, "index.html"),
+		"js/lib/app.js":  filepath.Join(dir, "js", "lib", "app.js"),
+		"js/./app.js":    filepath.Join(dir, "js", "app.js"),
+		"../escaped":     "",
+		"js/../../up":    "",
+		"..":             "",
+		"js\\..\\..\\up": "",
+		"/etc/passwd":    "",
+	} {
+		got, err := LocalPath(dir, entryPath)
+		if (err == nil) != (expected != "") || got != expected {
+			logger.Errorf("FAILURE: improper local path of %s. expected: %s\tgot: %s:%v\n", entryPath, expected, got, err)
+			t.Fail()
+		}
+	}
+}
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "eris_ipfs_")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	os.MkdirAll(filepath.Join(dir, "js", "lib"), 0755)
+	os.MkdirAll(filepath.Join(dir, "empty"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "js", "lib", "app.js"), []byte("var x;"), 0644)
+	return dir
+}
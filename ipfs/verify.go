@@ -0,0 +1,138 @@
+package ipfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// the largest file IPFS keeps in a single block when adding it with
+// its default chunker; anything larger is a tree of blocks
+const blockSize = 256 * 1024
+
+// multihash function code for sha2-256
+const sha2_256 = 0x12
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// verify checks the downloaded file fileName is the file hash. Files
+// which fit in one block are hashed here; larger ones, and hashes made
+// some other way, are hashed by the node itself without adding them.
+func (c *Client) verify(hash, fileName string) error {
+	if strings.Contains(hash, "/") {
+		logger.Debugf("Cannot check a path =>\t\t%s\n", hash)
+		return nil
+	}
+	code, digest, err := decodeMultihash(hash)
+	if err != nil {
+		return err
+	}
+
+	var got string
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	if code == sha2_256 && len(digest) == sha256.Size && info.Size() <= blockSize {
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		got = fileHash(data)
+	} else if got, err = c.Hash(fileName); err != nil {
+		return err
+	}
+
+	if got != hash {
+		return fmt.Errorf("The content IPFS sent does not match its hash. Expected %s, got %s.", hash, got)
+	}
+	logger.Debugf("Content matches =>\t\t%s\n", hash)
+	return nil
+}
+
+// fileHash is the hash IPFS gives a file of one block: the sha2-256
+// multihash of a DAG node holding the file's unixfs data.
+func fileHash(data []byte) string {
+	// unixfs Data{Type: File, Data: data, filesize: len(data)}
+	var unixfs bytes.Buffer
+	unixfs.Write([]byte{0x08, 0x02})
+	if len(data) > 0 {
+		unixfs.WriteByte(0x12)
+		unixfs.Write(uvarint(uint64(len(data))))
+		unixfs.Write(data)
+	}
+	unixfs.WriteByte(0x18)
+	unixfs.Write(uvarint(uint64(len(data))))
+
+	// PBNode{Data: unixfs}
+	var node bytes.Buffer
+	node.WriteByte(0x0a)
+	node.Write(uvarint(uint64(unixfs.Len())))
+	node.Write(unixfs.Bytes())
+
+	sum := sha256.Sum256(node.Bytes())
+	return base58Encode(append([]byte{sha2_256, sha256.Size}, sum[:]...))
+}
+
+// decodeMultihash splits a base58 IPFS hash into its hash function
+// code and digest.
+func decodeMultihash(hash string) (byte, []byte, error) {
+	raw, err := base58Decode(hash)
+	if err != nil || len(raw) < 2 || int(raw[1]) != len(raw)-2 {
+		return 0, nil, fmt.Errorf("%s is not an IPFS hash.", hash)
+	}
+	return raw[0], raw[2:], nil
+}
+
+func uvarint(x uint64) []byte {
+	buf := []byte{}
+	for x >= 0x80 {
+		buf = append(buf, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(buf, byte(x))
+}
+
+func base58Encode(raw []byte) string {
+	n := new(big.Int).SetBytes(raw)
+	base, mod := big.NewInt(58), new(big.Int)
+	out := []byte{}
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range raw {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n, base := new(big.Int), big.NewInt(58)
+	for _, r := range s {
+		i := strings.IndexRune(base58Alphabet, r)
+		if i < 0 {
+			return nil, fmt.Errorf("bad base58 character %q", r)
+		}
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	raw := n.Bytes()
+	for _, r := range s {
+		if r != rune(base58Alphabet[0]) {
+			break
+		}
+		raw = append([]byte{0}, raw...)
+	}
+	return raw, nil
+}
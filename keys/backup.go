@@ -0,0 +1,346 @@
+package keys
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// PassphraseVar is read for the backup passphrase before falling back
+// to asking on stdin.
+const PassphraseVar = "ERIS_KEYS_PASSPHRASE"
+
+// the keys container's home for key files and key names
+const keysContainerPath = "/home/eris/.eris/keys"
+
+// A backup is
+//
+//	magic | pbkdf2 iterations (uint32) | salt | nonce | AES-256-GCM ciphertext
+//
+// with everything before the ciphertext authenticated as additional data,
+// so a wrong passphrase and a damaged file are both caught on open.
+var backupMagic = []byte("ERISKEYS\x01")
+
+const (
+	backupIterations = 100000
+	// the iterations a backup header may ask for; more would let a
+	// crafted file tie up the cpu, fewer would be no protection
+	backupMinIterations = 10000
+	backupMaxIterations = 100 * backupIterations
+	backupSaltSize      = 16
+	backupKeySize       = 32
+	backupHeaderSize    = 9 + 4 + backupSaltSize + 12
+)
+
+// BackupKeys writes every key and key name held in the keys data
+// container to do.Path as a passphrase encrypted archive.
+func BackupKeys(do *definitions.Do) error {
+	if do.Path == "" {
+		return fmt.Errorf("Please tell the marmots where to write the backup with --out.")
+	}
+
+	cont := util.FindDataContainer("keys", 1)
+	if cont == nil {
+		return fmt.Errorf("The marmots could not find the keys data container.\nStart the keys service once with: [eris services start keys]")
+	}
+
+	archive, err := copyKeysOut(cont.ContainerID)
+	if err != nil {
+		return err
+	}
+	files, err := tarFiles(archive)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := keysPassphrase(true)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealBackup(archive, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(do.Path, sealed, 0600); err != nil {
+		return err
+	}
+	logger.Printf("Backed up %d key files to =>\t%s\n", len(files), do.Path)
+	do.Result = do.Path
+	return nil
+}
+
+// RestoreKeys puts the keys from the backup at do.Path back into the
+// keys data container. Keys which already exist with different contents
+// are only overwritten with do.Force.
+func RestoreKeys(do *definitions.Do) error {
+	sealed, err := ioutil.ReadFile(do.Path)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := keysPassphrase(false)
+	if err != nil {
+		return err
+	}
+	archive, err := openBackup(sealed, passphrase)
+	if err != nil {
+		return err
+	}
+	restoring, err := tarFiles(archive)
+	if err != nil {
+		return err
+	}
+
+	// the keys service makes its data container the first time it runs
+	if _, err := KeysClient(); err != nil {
+		return err
+	}
+	cont := util.FindDataContainer("keys", 1)
+	if cont == nil {
+		return fmt.Errorf("The marmots could not find the keys data container.")
+	}
+
+	current, err := copyKeysOut(cont.ContainerID)
+	if err != nil {
+		return err
+	}
+	existing, err := tarFiles(current)
+	if err != nil {
+		return err
+	}
+
+	if conflicts := overwrites(existing, restoring); len(conflicts) != 0 {
+		if !do.Force {
+			return fmt.Errorf("The backup would overwrite these existing keys:\n\n%s\n\nRestore anyway with --force.", strings.Join(conflicts, "\n"))
+		}
+		logger.Infof("Overwriting =>\t\t\t%s\n", strings.Join(conflicts, ", "))
+	}
+
+	if err := perform.DockerImportTar(cont.ContainerID, path.Dir(keysContainerPath), bytes.NewReader(archive)); err != nil {
+		return err
+	}
+	logger.Printf("Restored %d key files from =>\t%s\n", len(restoring), do.Path)
+	return nil
+}
+
+// copyKeysOut returns the keys directory of a container as a tar archive.
+func copyKeysOut(id string) ([]byte, error) {
+	var buf bytes.Buffer
+	opts := docker.CopyFromContainerOptions{
+		OutputStream: &buf,
+		Container:    id,
+		Resource:     keysContainerPath,
+	}
+	if err := util.DockerClient.CopyFromContainer(opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tarFiles maps the regular files of a tar archive to their contents' hash.
+func tarFiles(archive []byte) (map[string]string, error) {
+	files := map[string]string{}
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("The marmots could not read the keys archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		sum := sha256.New()
+		if _, err := io.Copy(sum, reader); err != nil {
+			return nil, err
+		}
+		files[path.Clean(header.Name)] = fmt.Sprintf("%x", sum.Sum(nil))
+	}
+}
+
+// the files restoring would change
+func overwrites(existing, restoring map[string]string) []string {
+	conflicts := []string{}
+	for name, sum := range restoring {
+		if old, ok := existing[name]; ok && old != sum {
+			conflicts = append(conflicts, name)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+func keysPassphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(PassphraseVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	// typed on a terminal the passphrase is not echoed; piped in it is
+	// read as a line
+	stdin := bufio.NewReader(os.Stdin)
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		if !isTerminal(os.Stdin.Fd()) {
+			return readLine(stdin)
+		}
+		line, err := readPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		return line, err
+	}
+
+	passphrase, err := ask("Backup passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("The marmots will not use an empty passphrase.")
+	}
+
+	if confirm {
+		again, err := ask("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != passphrase {
+			return nil, fmt.Errorf("The passphrases do not match.")
+		}
+	}
+	return []byte(passphrase), nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readUnbuffered reads a line a byte at a time, so nothing after it is
+// taken from the terminal.
+func readUnbuffered(reader io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := reader.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+func sealBackup(plain, passphrase []byte) ([]byte, error) {
+	header := make([]byte, backupHeaderSize)
+	copy(header, backupMagic)
+	binary.BigEndian.PutUint32(header[len(backupMagic):], backupIterations)
+	salt := header[len(backupMagic)+4 : len(backupMagic)+4+backupSaltSize]
+	nonce := header[len(backupMagic)+4+backupSaltSize:]
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := backupCipher(passphrase, salt, backupIterations)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+func openBackup(sealed, passphrase []byte) ([]byte, error) {
+	if len(sealed) < backupHeaderSize || !bytes.Equal(sealed[:len(backupMagic)], backupMagic) {
+		return nil, fmt.Errorf("The marmots do not recognize this as a keys backup.")
+	}
+
+	header := sealed[:backupHeaderSize]
+	iterations := int(binary.BigEndian.Uint32(header[len(backupMagic):]))
+	if iterations < backupMinIterations || iterations > backupMaxIterations {
+		return nil, fmt.Errorf("The marmots will not open a backup asking for %d key derivation rounds. Is it damaged?", iterations)
+	}
+	salt := header[len(backupMagic)+4 : len(backupMagic)+4+backupSaltSize]
+	nonce := header[len(backupMagic)+4+backupSaltSize:]
+
+	aead, err := backupCipher(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, sealed[backupHeaderSize:], header)
+	if err != nil {
+		return nil, fmt.Errorf("The marmots could not open the backup. Either the passphrase is wrong or the file is damaged.")
+	}
+	return plain, nil
+}
+
+func backupCipher(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2(passphrase, salt, iterations, backupKeySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a key from a passphrase as in RFC 2898.
+func pbkdf2(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	blocks := (keyLen + size - 1) / size
+
+	key := make([]byte, 0, blocks*size)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+
+		t := make([]byte, size)
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
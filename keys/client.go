@@ -0,0 +1,149 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+)
+
+// port the eris-keys daemon listens on inside its container
+const KeysPort = "4767/tcp"
+
+// DefaultKeyType is what eris-keys makes when no type is given.
+const DefaultKeyType = "ed25519,ripemd160"
+
+// how long a single call to the keys daemon is allowed to take
+var keysTimeout = 10 * time.Second
+
+// Client talks to an eris-keys daemon over its HTTP API.
+type Client struct {
+	Addr string // host:port
+}
+
+// every eris-keys endpoint answers with this
+type keysResponse struct {
+	Response string
+	Error    string
+}
+
+// KeysClient makes sure the keys service is running and returns
+// a client for it.
+func KeysClient() (*Client, error) {
+	keysService, err := loaders.LoadServiceDefinition("keys", false, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := perform.DockerRun(keysService.Service, keysService.Operations); err != nil {
+		return nil, err
+	}
+
+	cont := util.FindServiceContainer("keys", 1, true)
+	if cont == nil {
+		return nil, fmt.Errorf("The marmots could not find a running keys service.\nStart it with: [eris services start keys]")
+	}
+
+	info, err := util.DockerClient.InspectContainer(cont.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := util.ContainerAddress(info, KeysPort)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("Keys daemon found at =>\t%s\n", addr)
+	return &Client{Addr: addr}, nil
+}
+
+// Gen makes a new key and returns its address. The key is named when
+// name is given.
+func (c *Client) Gen(keyType, name string) (string, error) {
+	if keyType == "" {
+		keyType = DefaultKeyType
+	}
+	return c.call("gen", map[string]string{"type": keyType, "name": name, "auth": ""})
+}
+
+// Pub returns the hex encoded public key of a key given by address or name.
+func (c *Client) Pub(addr, name string) (string, error) {
+	return c.call("pub", map[string]string{"addr": addr, "name": name})
+}
+
+// Import hands the daemon a key, either a key file's contents or a hex
+// encoded private key, and returns its address.
+func (c *Client) Import(key, keyType, name string) (string, error) {
+	if keyType == "" {
+		keyType = DefaultKeyType
+	}
+	return c.call("import", map[string]string{"key": key, "type": keyType, "name": name, "auth": ""})
+}
+
+// Names returns the named keys and their addresses.
+func (c *Client) Names() (map[string]string, error) {
+	res, err := c.call("name/ls", map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	if res == "" {
+		return names, nil
+	}
+	if err := json.Unmarshal([]byte(res), &names); err != nil {
+		return nil, fmt.Errorf("The marmots could not understand the key names: %v", err)
+	}
+	return names, nil
+}
+
+// Resolve turns a key name into its address. Anything which is not
+// a known name is taken to be an address already.
+func (c *Client) Resolve(nameOrAddr string) (string, error) {
+	names, err := c.Names()
+	if err != nil {
+		return "", err
+	}
+	if addr, ok := names[nameOrAddr]; ok {
+		return addr, nil
+	}
+	return nameOrAddr, nil
+}
+
+func (c *Client) call(method string, args map[string]string) (string, error) {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debugf("Calling keys daemon =>\t\t%s:%s\n", c.Addr, method)
+	client := &http.Client{Timeout: keysTimeout}
+	response, err := client.Post("http://"+c.Addr+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	raw, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var res keysResponse
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return "", fmt.Errorf("The marmots could not understand the keys daemon's response to %s (status %d): %v", method, response.StatusCode, err)
+	}
+	if res.Error != "" {
+		return "", fmt.Errorf("Keys error on %s: %s", method, res.Error)
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("Keys error on %s: %s", method, response.Status)
+	}
+	return res.Response, nil
+}
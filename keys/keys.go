@@ -0,0 +1,280 @@
+package keys
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/util"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// where eris-keys keeps its key files inside the keys container
+const keysDataContainerPath = "/home/eris/.eris/keys/data"
+
+// the unencrypted key file format of eris-keys
+type keyFile struct {
+	Id         []byte
+	Type       string
+	Address    string
+	PrivateKey []byte
+}
+
+// PrivValidator is the priv_validator.json a chain's node signs with.
+type PrivValidator struct {
+	Address    string        `json:"address"`
+	PubKey     []interface{} `json:"pub_key"`
+	PrivKey    []interface{} `json:"priv_key"`
+	LastHeight int           `json:"last_height"`
+	LastRound  int           `json:"last_round"`
+	LastStep   int           `json:"last_step"`
+}
+
+// type byte of ed25519 keys in priv_validator.json
+const ed25519TypeByte = 1
+
+func GenKey(do *definitions.Do) error {
+	c, err := KeysClient()
+	if err != nil {
+		return err
+	}
+
+	addr, err := c.Gen(do.Type, do.Name)
+	if err != nil {
+		return err
+	}
+	logger.Println(addr)
+	do.Result = addr
+	return nil
+}
+
+func ListKeys(do *definitions.Do) error {
+	c, err := KeysClient()
+	if err != nil {
+		return err
+	}
+
+	names, err := c.Names()
+	if err != nil {
+		return err
+	}
+
+	list := []string{}
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	for _, name := range list {
+		logger.Printf("%s\t%s\n", names[name], name)
+	}
+	do.Result = strings.Join(list, "\n")
+	return nil
+}
+
+// ShowKey prints the address and public key of the key do.Name.
+func ShowKey(do *definitions.Do) error {
+	c, err := KeysClient()
+	if err != nil {
+		return err
+	}
+
+	addr, err := c.Resolve(do.Name)
+	if err != nil {
+		return err
+	}
+	pub, err := c.Pub(addr, "")
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("Address =>\t\t\t%s\n", addr)
+	logger.Printf("Public Key =>\t\t\t%s\n", pub)
+	do.Result = pub
+	return nil
+}
+
+// ImportKey imports do.Path, either a key file or a hex encoded
+// private key, under the name do.Name.
+func ImportKey(do *definitions.Do) error {
+	c, err := KeysClient()
+	if err != nil {
+		return err
+	}
+
+	key := do.Path
+	if raw, err := ioutil.ReadFile(do.Path); err == nil {
+		key = string(raw)
+	}
+
+	addr, err := c.Import(key, do.Type, do.Name)
+	if err != nil {
+		return err
+	}
+	logger.Println(addr)
+	do.Result = addr
+	return nil
+}
+
+// ExportKey copies the key file of do.Name out of the keys container
+// into the host's keys directory.
+func ExportKey(do *definitions.Do) error {
+	c, err := KeysClient()
+	if err != nil {
+		return err
+	}
+
+	addr, err := c.Resolve(do.Name)
+	if err != nil {
+		return err
+	}
+
+	file, err := exportKeyFile(addr)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Key exported to =>\t\t%s\n", file)
+	do.Result = file
+	return nil
+}
+
+// ConvertKey prints the key do.Name as a priv_validator.json.
+func ConvertKey(do *definitions.Do) error {
+	pv, err := ValidatorKey(do.Name)
+	if err != nil {
+		return err
+	}
+	logger.Println(string(pv))
+	do.Result = string(pv)
+	return nil
+}
+
+// ValidatorKey finds the key name (or address) in the keys service and
+// returns it as a priv_validator.json. eris chains new --key uses it to
+// make a chain validated by that key.
+func ValidatorKey(name string) ([]byte, error) {
+	c, err := KeysClient()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := c.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return PrivValidatorJSON(addr)
+}
+
+// PrivValidatorJSON reads the key at addr from the keys container and
+// returns it as a priv_validator.json for chain setup to use. The key
+// is never written to the host.
+func PrivValidatorJSON(addr string) ([]byte, error) {
+	raw, err := readKeyFile(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	pv, err := privValidatorFromKeyFile(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(pv, "", "  ")
+}
+
+func privValidatorFromKeyFile(raw []byte) (*PrivValidator, error) {
+	var key keyFile
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the key file (is it encrypted?): %v", err)
+	}
+
+	if !strings.HasPrefix(key.Type, "ed25519") {
+		return nil, fmt.Errorf("Only ed25519 keys can be validators, not (%s).", key.Type)
+	}
+	if len(key.PrivateKey) != 64 {
+		return nil, fmt.Errorf("Bad ed25519 private key length. expected: 64\tgot: %d", len(key.PrivateKey))
+	}
+
+	return &PrivValidator{
+		Address: strings.ToUpper(key.Address),
+		PubKey:  []interface{}{ed25519TypeByte, strings.ToUpper(hex.EncodeToString(key.PrivateKey[32:]))},
+		PrivKey: []interface{}{ed25519TypeByte, strings.ToUpper(hex.EncodeToString(key.PrivateKey))},
+	}, nil
+}
+
+// exportKeyFile copies a key out of the keys container and returns
+// the path of its key file on the host.
+func exportKeyFile(addr string) (string, error) {
+	reader, err := copyKeyFile(addr)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(KeysDataPath, 0700); err != nil {
+		return "", err
+	}
+	if err := util.Untar(reader, addr, KeysDataPath); err != nil {
+		return "", err
+	}
+
+	return path.Join(KeysDataPath, addr, addr), nil
+}
+
+// readKeyFile reads a key file straight out of the keys container.
+func readKeyFile(addr string) ([]byte, error) {
+	reader, err := copyKeyFile(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return keyFromTar(reader, addr)
+}
+
+// keyFromTar finds the key file of addr in a tar archive of its
+// directory.
+func keyFromTar(reader io.Reader, addr string) ([]byte, error) {
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("The marmots could not find the key file of %s in the keys container.", addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Base(header.Name) == addr && (header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA) {
+			return ioutil.ReadAll(archive)
+		}
+	}
+}
+
+// copyKeyFile streams the directory of the key at addr out of the keys
+// container as a tar archive.
+func copyKeyFile(addr string) (io.ReadCloser, error) {
+	cont := util.FindServiceContainer("keys", 1, true)
+	if cont == nil {
+		return nil, fmt.Errorf("The marmots could not find a running keys service.\nStart it with: [eris services start keys]")
+	}
+
+	reader, writer := io.Pipe()
+	opts := docker.CopyFromContainerOptions{
+		OutputStream: writer,
+		Container:    cont.ContainerID,
+		Resource:     path.Join(keysDataContainerPath, addr),
+	}
+
+	go func() {
+		writer.CloseWithError(util.DockerClient.CopyFromContainer(opts))
+	}()
+	return reader, nil
+}
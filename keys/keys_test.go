@@ -0,0 +1,238 @@
+package keys
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKeysClient(t *testing.T) {
+	server := httptest.NewServer(newStubKeys())
+	defer server.Close()
+	c := &Client{Addr: strings.TrimPrefix(server.URL, "http://")}
+
+	addr, err := c.Gen("", "validator")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	names, err := c.Names()
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if names["validator"] != addr {
+		logger.Errorf("FAILURE: improper key names on LS. expected: %s\tgot: %v\n", addr, names)
+		t.Fail()
+	}
+
+	resolved, err := c.Resolve("validator")
+	if err != nil || resolved != addr {
+		logger.Errorf("FAILURE: improper address on RESOLVE. expected: %s\tgot: %s:%v\n", addr, resolved, err)
+		t.Fail()
+	}
+
+	pub, err := c.Pub(addr, "")
+	if err != nil || pub != "PUB"+addr {
+		logger.Errorf("FAILURE: improper public key on PUB. expected: %s\tgot: %s:%v\n", "PUB"+addr, pub, err)
+		t.Fail()
+	}
+
+	if _, err := c.Pub("nope", ""); err == nil {
+		logger.Errorf("FAILURE: expected an error on PUB of an unknown key.\n")
+		t.Fail()
+	}
+
+	imported, err := c.Import("deadbeef", "", "imported")
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if names, _ := c.Names(); names["imported"] != imported {
+		logger.Errorf("FAILURE: imported key not named. expected: %s\tgot: %v\n", imported, names)
+		t.Fail()
+	}
+}
+
+func TestPrivValidatorFromKeyFile(t *testing.T) {
+	priv := make([]byte, 64)
+	for i := range priv {
+		priv[i] = byte(i)
+	}
+	raw, _ := json.Marshal(keyFile{Type: DefaultKeyType, Address: "abcd", PrivateKey: priv})
+
+	pv, err := privValidatorFromKeyFile(raw)
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+
+	if pv.Address != "ABCD" {
+		logger.Errorf("FAILURE: improper address on CONVERT. expected: %s\tgot: %s\n", "ABCD", pv.Address)
+		t.Fail()
+	}
+
+	pub := fmt.Sprintf("%X", priv[32:])
+	if pv.PubKey[1] != pub || pv.PrivKey[1] != fmt.Sprintf("%X", priv) {
+		logger.Errorf("FAILURE: improper keys on CONVERT. expected: %s\tgot: %v\n", pub, pv.PubKey[1])
+		t.Fail()
+	}
+
+	raw, _ = json.Marshal(keyFile{Type: "secp256k1,sha3", Address: "abcd", PrivateKey: priv[:32]})
+	if _, err := privValidatorFromKeyFile(raw); err == nil {
+		logger.Errorf("FAILURE: expected an error converting a secp256k1 key.\n")
+		t.Fail()
+	}
+}
+
+func TestKeyFromTar(t *testing.T) {
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	archive.WriteHeader(&tar.Header{Name: "ABCD/", Mode: 0700, Typeflag: tar.TypeDir})
+	archive.WriteHeader(&tar.Header{Name: "ABCD/ABCD", Mode: 0600, Size: 6, Typeflag: tar.TypeReg})
+	archive.Write([]byte("secret"))
+	archive.Close()
+
+	raw, err := keyFromTar(bytes.NewReader(buf.Bytes()), "ABCD")
+	if err != nil || string(raw) != "secret" {
+		logger.Errorf("FAILURE: improper key read. expected: %s\tgot: %s:%v\n", "secret", raw, err)
+		t.Fail()
+	}
+	if _, err := keyFromTar(bytes.NewReader(buf.Bytes()), "EF01"); err == nil {
+		logger.Errorf("FAILURE: expected an error reading a missing key.\n")
+		t.Fail()
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	for name, body := range map[string]string{"keys/data/ABCD/ABCD": "secret", "keys/names/validator": "ABCD"} {
+		archive.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		archive.Write([]byte(body))
+	}
+	archive.Close()
+	plain := buf.Bytes()
+
+	sealed, err := sealBackup(plain, []byte("marmots"))
+	if err != nil {
+		logger.Errorln(err)
+		t.FailNow()
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		logger.Errorf("FAILURE: key found in plain text in the backup.\n")
+		t.Fail()
+	}
+
+	opened, err := openBackup(sealed, []byte("marmots"))
+	if err != nil || !bytes.Equal(opened, plain) {
+		logger.Errorf("FAILURE: backup did not round trip: %v\n", err)
+		t.FailNow()
+	}
+
+	if _, err := openBackup(sealed, []byte("beavers")); err == nil {
+		logger.Errorf("FAILURE: expected an error opening with the wrong passphrase.\n")
+		t.Fail()
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := openBackup(sealed, []byte("marmots")); err == nil {
+		logger.Errorf("FAILURE: expected an error opening a damaged backup.\n")
+		t.Fail()
+	}
+
+	for _, iterations := range []uint32{0, backupMinIterations - 1, backupMaxIterations + 1, 1<<32 - 1} {
+		crafted := append([]byte{}, sealed...)
+		binary.BigEndian.PutUint32(crafted[len(backupMagic):], iterations)
+		if _, err := openBackup(crafted, []byte("marmots")); err == nil || !strings.Contains(err.Error(), "rounds") {
+			logger.Errorf("FAILURE: expected %d rounds to be refused. got: %v\n", iterations, err)
+			t.Fail()
+		}
+	}
+
+	files, err := tarFiles(plain)
+	if err != nil || len(files) != 2 {
+		logger.Errorf("FAILURE: improper files in the backup. expected: 2\tgot: %v:%v\n", files, err)
+		t.FailNow()
+	}
+	existing := map[string]string{"keys/data/ABCD/ABCD": files["keys/data/ABCD/ABCD"], "keys/names/validator": "changed"}
+	if conflicts := overwrites(existing, files); len(conflicts) != 1 || conflicts[0] != "keys/names/validator" {
+		logger.Errorf("FAILURE: improper conflicts on RESTORE. expected: %s\tgot: %v\n", "keys/names/validator", conflicts)
+		t.Fail()
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	for _, v := range []struct {
+		iterations int
+		key        string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	} {
+		key := fmt.Sprintf("%x", pbkdf2([]byte("password"), []byte("salt"), v.iterations, 32, sha256.New))
+		if key != v.key {
+			logger.Errorf("FAILURE: improper pbkdf2 key. expected: %s\tgot: %s\n", v.key, key)
+			t.Fail()
+		}
+	}
+}
+
+// stubKeys stands in for the eris-keys daemon.
+type stubKeys struct {
+	sync.Mutex
+	count int
+	names map[string]string
+	addrs map[string]bool
+}
+
+func newStubKeys() *stubKeys {
+	return &stubKeys{names: map[string]string{}, addrs: map[string]bool{}}
+}
+
+func (k *stubKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+
+	var args map[string]string
+	json.NewDecoder(r.Body).Decode(&args)
+
+	var res keysResponse
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "gen", "import":
+		k.count++
+		addr := fmt.Sprintf("%040X", k.count)
+		k.addrs[addr] = true
+		if args["name"] != "" {
+			k.names[args["name"]] = addr
+		}
+		res.Response = addr
+	case "pub":
+		addr := args["addr"]
+		if args["name"] != "" {
+			addr = k.names[args["name"]]
+		}
+		if !k.addrs[addr] {
+			res.Error = "unknown key " + addr
+		} else {
+			res.Response = "PUB" + addr
+		}
+	case "name/ls":
+		names, _ := json.Marshal(k.names)
+		res.Response = string(names)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		res.Error = "unknown method " + r.URL.Path
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
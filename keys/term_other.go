@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package keys
+
+import "fmt"
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func readPassword(fd uintptr) (string, error) {
+	return "", fmt.Errorf("The marmots cannot hide the passphrase here. Pipe it in or set %s.", PassphraseVar)
+}
@@ -0,0 +1,30 @@
+package keys
+
+import (
+	"os"
+	"syscall"
+)
+
+// the console mode bit which echoes what is typed
+const enableEchoInput = 0x4
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
+
+// readPassword reads a line from the console fd without echoing it.
+func readPassword(fd uintptr) (string, error) {
+	var old uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(fd), &old); err != nil {
+		return "", err
+	}
+	if ok, _, err := setConsoleMode.Call(fd, uintptr(old&^enableEchoInput)); ok == 0 {
+		return "", err
+	}
+	defer setConsoleMode.Call(fd, uintptr(old))
+
+	return readUnbuffered(os.NewFile(fd, "CONIN$"))
+}
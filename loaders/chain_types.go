@@ -0,0 +1,133 @@
+package loaders
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/util"
+	"github.com/eris-ltd/eris-cli/version"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// DefaultChainType is used by chain definitions without a chain_type.
+const DefaultChainType = "mint"
+
+// chain types known without any files in the eris root. files
+// in ChainTypesPath() with the same name are layered on top.
+var chainTypes = map[string]func() *definitions.ChainType{
+	"mint": MintChainType,
+	"eth":  EthChainType,
+}
+
+// RegisterChainType makes a chain type available to chain
+// definitions under the given name.
+func RegisterChainType(name string, typ func() *definitions.ChainType) {
+	chainTypes[name] = typ
+}
+
+// ChainTypesPath is the directory holding user defined chain types,
+// one <name>.toml (or .json, .yaml) file per type.
+func ChainTypesPath() string {
+	return path.Join(BlockchainsPath, "types")
+}
+
+func MintChainType() *definitions.ChainType {
+	typ := definitions.BlankChainType()
+	typ.Name = "mint"
+	typ.Image = "eris/erisdb:" + strings.Join(strings.Split(version.VERSION, ".")[:2], ".") // only need 0.10 not full ver => 0.10.0
+	typ.NewCmd = ErisChainNew
+	typ.InstallCmd = ErisChainInstall
+	typ.StartCmd = ErisChainStart
+	typ.APICmd = ErisChainStartApi
+	typ.RPCPort = ErisChainRPCPort
+	typ.ChainsDir = "blockchains"
+	typ.GenesisFile = "genesis.json"
+	typ.ConfigFile = "config.toml"
+	return typ
+}
+
+// eth dev chains do not speak the tendermint rpc
+// so status and wait are not available for them.
+func EthChainType() *definitions.ChainType {
+	typ := definitions.BlankChainType()
+	typ.Name = "eth"
+	typ.Image = "eris/eth"
+	typ.NewCmd = "eth-wrapper new"
+	typ.InstallCmd = "eth-wrapper install"
+	typ.StartCmd = "eth-wrapper run"
+	typ.APICmd = "eth-wrapper run"
+	typ.ChainsDir = "blockchains"
+	typ.GenesisFile = "genesis.json"
+	return typ
+}
+
+// LoadChainType finds a chain type by name. A file in ChainTypesPath()
+// overrides the fields it sets of the built in type of the same name,
+// or defines a new type altogether.
+func LoadChainType(name string) (*definitions.ChainType, error) {
+	if name == "" {
+		name = DefaultChainType
+	}
+
+	typ := definitions.BlankChainType()
+	builtIn, ok := chainTypes[name]
+	if ok {
+		typ = builtIn()
+	}
+
+	if hasChainTypeFile(name) {
+		conf, err := util.LoadViperConfig(ChainTypesPath(), name, "chain type")
+		if err != nil {
+			return nil, err
+		}
+		if err := conf.Marshal(typ); err != nil {
+			return nil, fmt.Errorf("The marmots could not marshal the chain type (%s): %v", name, err)
+		}
+		ok = true
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown chain type (%s).\nKnown types =>\t\t\t%s\nAdd one by writing %s.", name, strings.Join(KnownChainTypes(), ", "), path.Join(ChainTypesPath(), name+".toml"))
+	}
+
+	typ.Name = name
+	logger.Debugf("Chain Type Loaded =>\t\t%s:%s\n", typ.Name, typ.Image)
+	return typ, nil
+}
+
+// KnownChainTypes lists the names of built in and file defined chain types.
+func KnownChainTypes() []string {
+	names := map[string]bool{}
+	for name := range chainTypes {
+		names[name] = true
+	}
+
+	files, _ := ioutil.ReadDir(ChainTypesPath())
+	for _, f := range files {
+		if !f.IsDir() {
+			names[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = true
+		}
+	}
+
+	var known []string
+	for name := range names {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	return known
+}
+
+func hasChainTypeFile(name string) bool {
+	files, _ := ioutil.ReadDir(ChainTypesPath())
+	for _, f := range files {
+		if !f.IsDir() && strings.TrimSuffix(f.Name(), path.Ext(f.Name())) == name {
+			return true
+		}
+	}
+	return false
+}
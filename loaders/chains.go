@@ -7,7 +7,6 @@ import (
 
 	"github.com/eris-ltd/eris-cli/definitions"
 	"github.com/eris-ltd/eris-cli/util"
-	"github.com/eris-ltd/eris-cli/version"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 
@@ -19,6 +18,7 @@ const (
 	ErisChainStartApi = "erisdb-wrapper api"
 	ErisChainInstall  = "erisdb-wrapper install"
 	ErisChainNew      = "erisdb-wrapper new"
+	ErisChainRPCPort  = "46657/tcp"
 )
 
 // viper read config file, marshal to definition struct,
@@ -63,33 +63,74 @@ func ChainsAsAService(chainName string, newCont bool, cNum ...int) (*definitions
 	if err != nil {
 		return nil, err
 	}
-	return ServiceDefFromChain(chain, ErisChainStart), nil
+	typ, err := LoadChainType(chain.ChainType)
+	if err != nil {
+		return nil, err
+	}
+	return ServiceDefFromChain(chain, typ.StartCmd), nil
 }
 
 func ServiceDefFromChain(chain *definitions.Chain, cmd string) *definitions.ServiceDefinition {
 	// chainID := chain.ChainID
-	vers := strings.Join(strings.Split(version.VERSION, ".")[0:2], ".")
 	chain.Service.Name = chain.Name // this let's the data containers flow thru
-	chain.Service.Image = "eris/erisdb:" + vers
-	chain.Service.AutoData = true // default. they can turn it off. it's like BarBri
+	chain.Service.AutoData = true   // default. they can turn it off. it's like BarBri
 	setChainDefaults(chain)
 	chain.Service.Command = cmd
 
 	srv := &definitions.ServiceDefinition{
-		Name:        chain.Name,
-		ServiceID:   chain.ChainID,
-		ServiceDeps: []string{"keys"},
-		Service:     chain.Service,
-		Operations:  chain.Operations,
-		Maintainer:  chain.Maintainer,
-		Location:    chain.Location,
-		Machine:     chain.Machine,
+		Name:          chain.Name,
+		ServiceID:     chain.ChainID,
+		ServiceDeps:   []string{"keys"},
+		ChainName:     chain.Name,
+		ChainType:     chain.ChainType,
+		ErisDBVersion: chain.ErisDBVersion,
+		Service:       chain.Service,
+		Operations:    chain.Operations,
+		Maintainer:    chain.Maintainer,
+		Location:      chain.Location,
+		Machine:       chain.Machine,
 	}
 	ServiceFinalizeLoad(srv) // these are mostly operational considerations that we want to ensure are met
 
 	return srv
 }
 
+// ChainFromServiceDef is the inverse of ServiceDefFromChain. Services
+// which were not graduated from a chain become a chain of the default
+// type named after the service.
+func ChainFromServiceDef(srv *definitions.ServiceDefinition) *definitions.Chain {
+	chain := definitions.BlankChain()
+	chain.Name = util.OverWriteString(srv.ChainName, srv.Name)
+	chain.ChainID = util.OverWriteString(srv.ServiceID, chain.Name)
+	chain.ChainType = srv.ChainType
+	chain.ErisDBVersion = srv.ErisDBVersion
+
+	service := *srv.Service
+	chain.Service = &service
+	chain.Service.Command = "" // the chain type knows how to start it
+	env := []string{}
+	for _, e := range chain.Service.Environment {
+		if !strings.HasPrefix(e, "CHAIN_ID=") && !strings.HasPrefix(e, "CHAIN_TYPE=") {
+			env = append(env, e)
+		}
+	}
+	chain.Service.Environment = env
+
+	if srv.Maintainer != nil {
+		chain.Maintainer = srv.Maintainer
+	}
+	if srv.Location != nil {
+		chain.Location = srv.Location
+	}
+	if srv.Machine != nil {
+		chain.Machine = srv.Machine
+	}
+	chain.Operations.ContainerNumber = srv.Operations.ContainerNumber
+
+	checkChainNames(chain)
+	return chain
+}
+
 func MockChainDefinition(chainName, chainID string, newCont bool, cNum ...int) *definitions.Chain {
 	chn := definitions.BlankChain()
 	chn.Name = chainName
@@ -119,8 +160,23 @@ func MarshalChainDefinition(chainConf *viper.Viper, chain *definitions.Chain) er
 	}
 	// logger.Debugf("Loader.Chain.Marshal: ChanID =>\t%v\n", chnTemp.ChainID)
 
-	mergeChainAndService(chain, chnTemp.Service)
 	chain.ChainID = chnTemp.ChainID
+	chain.ErisDBVersion = chnTemp.ErisDBVersion
+	chain.Ephemeral = chnTemp.Ephemeral
+	chain.Created = chnTemp.Created
+	chain.TTL = chnTemp.TTL
+	chain.Maintainer = chnTemp.Maintainer
+	chain.Location = chnTemp.Location
+	chain.Machine = chnTemp.Machine
+	if chnTemp.ChainType != "" && chnTemp.ChainType != chain.ChainType {
+		if _, err := LoadChainType(chnTemp.ChainType); err != nil {
+			return err
+		}
+		chain.ChainType = chnTemp.ChainType
+		setChainDefaults(chain)
+	}
+	mergeChainAndService(chain, chnTemp.Service)
+	chain.Service.Image = PinImage(chain.Service.Image, chain.ErisDBVersion)
 
 	// toml bools don't really marshal well
 	// data_container can be in the chain or
@@ -137,14 +193,63 @@ func MarshalChainDefinition(chainConf *viper.Viper, chain *definitions.Chain) er
 }
 
 func setChainDefaults(chain *definitions.Chain) {
-	ver := strings.Join(strings.Split(version.VERSION, ".")[:2], ".") // only need 0.10 not full ver => 0.10.0
-	chain.Service.Image = "eris/erisdb:" + ver
+	typ, err := LoadChainType(chain.ChainType)
+	if err != nil {
+		logger.Infof("%v\nUsing the default chain type =>\t%s\n", err, DefaultChainType)
+		typ = MintChainType()
+	}
+	chain.ChainType = typ.Name
+	chain.Service.Image = PinImage(typ.Image, chain.ErisDBVersion)
+	if len(chain.Service.Ports) == 0 {
+		chain.Service.Ports = typ.Ports
+	}
 	chain.Service.AutoData = true
-	chain.ChainType = "mint"
-	chain.Service.Environment = append(chain.Service.Environment, "CHAIN_ID="+chain.ChainID, "CHAIN_TYPE="+chain.ChainType)
+	chain.Service.Environment = setEnv(chain.Service.Environment, "CHAIN_ID", chain.ChainID)
+	chain.Service.Environment = setEnv(chain.Service.Environment, "CHAIN_TYPE", chain.ChainType)
 	logger.Debugf("Chain Defaults Set. Image =>\t%s\n", chain.Service.Image)
 }
 
+// PinImage points image at the given version (tag) or sha256 digest.
+// An empty version leaves the image untouched.
+func PinImage(image, version string) string {
+	if version == "" {
+		return image
+	}
+
+	repo := ImageRepo(image)
+	if strings.HasPrefix(version, "sha256:") {
+		return repo + "@" + version
+	}
+	return repo + ":" + version
+}
+
+// ImageRepo strips the tag or digest from an image name.
+func ImageRepo(image string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		return image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// ImageVersion returns the tag or digest an image name is pinned to.
+func ImageVersion(image string) string {
+	return strings.TrimLeft(image[len(ImageRepo(image)):], ":@")
+}
+
+// setEnv replaces key in env or appends it when not yet present.
+func setEnv(env []string, key, value string) []string {
+	for i, e := range env {
+		if strings.HasPrefix(e, key+"=") {
+			env[i] = key + "=" + value
+			return env
+		}
+	}
+	return append(env, key+"="+value)
+}
+
 // read the config file into viper
 func loadChainDefinition(chainName string) (*viper.Viper, error) {
 	return util.LoadViperConfig(path.Join(BlockchainsPath), chainName, "chain")
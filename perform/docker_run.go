@@ -4,17 +4,20 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 
 	def "github.com/eris-ltd/eris-cli/definitions"
 	"github.com/eris-ltd/eris-cli/util"
 
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/code.google.com/p/go-uuid/uuid"
 	dirs "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 )
@@ -104,6 +107,136 @@ func DockerRunVolumesFromContainer(volumesFrom string, interactive bool, args []
 	return nil
 }
 
+// DockerImportTar unpacks the tar stream from reader into dest inside
+// a container with volumes-from the volumesFrom container. Ownership
+// recorded in the tar stream is kept.
+func DockerImportTar(volumesFrom, dest string, reader io.Reader) error {
+	logger.Infof("Importing into =>\t\t%s:%s\n", volumesFrom, dest)
+	return importTar(volumesFrom, []string{"tar", "xf", "-", "-C", dest}, reader)
+}
+
+// DockerReplaceTar is DockerImportTar after emptying dest, so dest ends
+// up holding exactly what is in the tar stream.
+func DockerReplaceTar(volumesFrom, dest string, reader io.Reader) error {
+	logger.Infof("Replacing =>\t\t\t%s:%s\n", volumesFrom, dest)
+	return importTar(volumesFrom, []string{"sh", "-c", `find "$0" -mindepth 1 -delete && tar xf - -C "$0"`, dest}, reader)
+}
+
+// DockerRemovePaths removes names, relative to dir, from inside a
+// container with volumes-from the volumesFrom container.
+func DockerRemovePaths(volumesFrom, dir string, names []string) error {
+	logger.Infof("Removing from =>\t\t%s:%s %v\n", volumesFrom, dir, names)
+	cmd := append([]string{"sh", "-c", `cd "$0" && rm -rf -- "$@"`, dir}, names...)
+	return importTar(volumesFrom, cmd, bytes.NewReader(nil))
+}
+
+func importTar(volumesFrom string, cmd []string, reader io.Reader) error {
+	opts := configureVolumesFromContainer(volumesFrom, false, cmd)
+	opts.Name = "eris_import_" + volumesFrom
+	return runWithInput(opts, reader)
+}
+
+// copyToVolume copies dir, from the volumes of the volumesFrom
+// container, into the named volume, keeping ownership.
+func copyToVolume(volumesFrom, dir, volume string) error {
+	cmd := []string{"sh", "-c", `cp -a "$0"/. /eris_volume/ && chown "$(stat -c %u:%g "$0")" /eris_volume`, dir}
+	opts := configureVolumesFromContainer(volumesFrom, false, cmd)
+	opts.Name = "eris_migrate_" + volumesFrom
+	opts.HostConfig.Binds = []string{volume + ":/eris_volume"}
+	return runWithInput(opts, bytes.NewReader(nil))
+}
+
+// checkVolume compares dir, in the volumes of the volumesFrom container,
+// with the named volume it was copied into.
+func checkVolume(volumesFrom, dir, volume string) error {
+	cmd := []string{"sh", "-c", `diff -r "$0" /eris_volume >&2`, dir}
+	opts := configureVolumesFromContainer(volumesFrom, false, cmd)
+	opts.Name = "eris_migrate_" + volumesFrom
+	opts.HostConfig.Binds = []string{volume + ":/eris_volume:ro"}
+	return runWithInput(opts, bytes.NewReader(nil))
+}
+
+// DockerHoldVolumes makes, without starting it, a container named
+// holder with each of binds (volume:dir) mounted, so named volumes no
+// service or chain container mounts can be read and written like a
+// data container. Remove it with DockerReleaseVolumes.
+func DockerHoldVolumes(holder, user string, binds []string) error {
+	opts := docker.CreateContainerOptions{
+		Name: holder,
+		Config: &docker.Config{
+			Image:           "eris/data",
+			User:            user,
+			NetworkDisabled: true,
+			Entrypoint:      []string{},
+			Cmd:             []string{"false"},
+		},
+		HostConfig: &docker.HostConfig{Binds: binds},
+	}
+	logger.Infof("Holding data volumes =>\t\t%s:%v\n", holder, binds)
+	_, err := createContainer(opts)
+	return err
+}
+
+// DockerReleaseVolumes removes a container made by DockerHoldVolumes,
+// leaving its volumes.
+func DockerReleaseVolumes(holder string) error {
+	return util.DockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: holder, Force: true})
+}
+
+// runWithInput runs a throwaway container made from opts with reader as
+// its stdin, and removes it after, whether it worked or not. opts.Name
+// gets a random suffix so runs against the same container at once, or
+// a helper left behind by a crash, do not clash.
+func runWithInput(opts docker.CreateContainerOptions, reader io.Reader) (err error) {
+	opts.Name += "_" + strings.Split(uuid.New(), "-")[0]
+	opts.Config.Tty = false
+	opts.Config.AttachStdin = true
+	opts.Config.OpenStdin = true
+
+	cont, err := createContainer(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err2 := util.DockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: cont.ID, Force: true})
+		if err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	// attach before starting so none of the stream is lost
+	var stderr bytes.Buffer
+	attached := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- util.DockerClient.AttachToContainer(docker.AttachToContainerOptions{
+			Container:    cont.ID,
+			InputStream:  reader,
+			OutputStream: ioutil.Discard,
+			ErrorStream:  &stderr,
+			Stream:       true,
+			Stdin:        true,
+			Stdout:       true,
+			Stderr:       true,
+			Success:      attached,
+		})
+	}()
+	select {
+	case <-attached:
+		attached <- struct{}{}
+	case err := <-done:
+		return err
+	}
+
+	if err := startContainer(cont.ID, &opts); err != nil {
+		return err
+	}
+	if err := waitContainer(cont.ID); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return <-done
+}
+
 func DockerRun(srv *def.Service, ops *def.Operation) error {
 	var id_main, id_data string
 	var optsData docker.CreateContainerOptions
@@ -130,9 +263,14 @@ func DockerRun(srv *def.Service, ops *def.Operation) error {
 		return err
 	}
 
-	// setup data container
+	// setup data container, or named volumes in its place
 	logger.Infof("Manage data containers? =>\t%t\n", srv.AutoData)
-	if srv.AutoData {
+	dataContainer := srv.AutoData && !srv.DataVolumes
+	if srv.AutoData && srv.DataVolumes {
+		if err := configureDataVolumes(srv, ops, &optsServ); err != nil {
+			return err
+		}
+	} else if srv.AutoData {
 		optsData, err = configureDataContainer(srv, ops, &optsServ)
 		if err != nil {
 			return err
@@ -143,7 +281,7 @@ func DockerRun(srv *def.Service, ops *def.Operation) error {
 	if servCont, exists := ContainerExists(ops); exists {
 		logger.Infoln("Service Container already exists, am not creating.")
 
-		if srv.AutoData {
+		if dataContainer {
 			if dataCont, exists = parseContainers(ops.DataContainerName, true); exists {
 				logger.Infoln("Data Container already exists, am not creating.")
 				id_data = dataCont.ID
@@ -162,7 +300,7 @@ func DockerRun(srv *def.Service, ops *def.Operation) error {
 	} else {
 		logger.Infof("Service Container does not exist, creating from image (%s).\n", srv.Image)
 
-		if srv.AutoData {
+		if dataContainer {
 			if dataCont, exists = parseContainers(ops.DataContainerName, true); exists {
 				logger.Infoln("Data Container already exists, am not creating.")
 				id_data = dataCont.ID
@@ -186,7 +324,7 @@ func DockerRun(srv *def.Service, ops *def.Operation) error {
 
 	// start the container
 	logger.Infof("Starting Service Contanr ID =>\t%s:%s\n", optsServ.Name, id_main)
-	if srv.AutoData {
+	if dataContainer {
 		logger.Infof("\twith DataContanr ID =>\t%s\n", id_data)
 	}
 	logger.Debugf("\twith CMD =>\t\t%v\n", optsServ.Config.Cmd)
@@ -257,38 +395,56 @@ func DockerExec(srv *def.Service, ops *def.Operation, cmd []string, interactive
 	}
 }
 
-func DockerRebuild(srv *def.Service, ops *def.Operation, skipPull bool, timeout uint) error {
-	var id string
-	var wasRunning bool = false
-
+func DockerRebuild(srv *def.Service, ops *def.Operation, skipPull bool, timeout uint) (err error) {
 	logger.Infof("Starting Docker Rebuild =>\t%s\n", srv.Name)
 
-	if service, exists := ContainerExists(ops); exists {
-		if _, running := ContainerRunning(ops); running {
-			wasRunning = true
-			err := DockerStop(srv, ops, timeout)
-			if err != nil {
-				return err
-			}
-		}
-
-		logger.Infof("Removing old container =>\t%s\n", service.ID)
-		err := removeContainer(service.ID)
-		if err != nil {
-			return err
-		}
-
-	} else {
+	service, exists := ContainerExists(ops)
+	if !exists {
 		logger.Infoln("Service did not previously exist. Nothing to rebuild.")
 		return nil
 	}
 
+	// pull before the old container is touched so a failed pull
+	// changes nothing
 	if skipPull {
-		logger.Infof("Pulling new image =>\t\t%s\n", srv.Image)
-		err := DockerPull(srv, ops)
+		if err := DockerPullImage(srv.Image); err != nil {
+			return err
+		}
+	}
+
+	// the old container is only set aside until the new one is up; if
+	// anything fails it is put back the way it was
+	j := NewJournal("Rebuild", false)
+	defer func() {
 		if err != nil {
+			err = j.Rollback(err)
+			return
+		}
+		err = j.Commit()
+	}()
+
+	_, wasRunning := ContainerRunning(ops)
+	if wasRunning {
+		if err = j.Record("stop "+ops.SrvContainerName, &Undo{Kind: UndoStartContainer, Args: []string{ops.SrvContainerName}}); err != nil {
 			return err
 		}
+		if err = DockerStop(srv, ops, timeout); err != nil {
+			return err
+		}
+	}
+
+	// a name which does not contain the service's, so the container
+	// is not mistaken for it while it is set aside
+	oldName := "rebuild_" + service.ID
+	if len(service.ID) > 12 {
+		oldName = "rebuild_" + service.ID[:12]
+	}
+	logger.Infof("Setting aside old container =>\t%s\n", service.ID)
+	if err = j.Record("set aside "+ops.SrvContainerName, &Undo{Kind: UndoRenameContainer, Args: []string{oldName, ops.SrvContainerName}}); err != nil {
+		return err
+	}
+	if err = renameContainer(service.ID, oldName); err != nil {
+		return err
 	}
 
 	opts, err := configureServiceContainer(srv, ops)
@@ -299,27 +455,162 @@ func DockerRebuild(srv *def.Service, ops *def.Operation, skipPull bool, timeout
 	if err != nil {
 		return err
 	}
+	if srv.AutoData && srv.DataVolumes {
+		if err = configureDataVolumes(srv, ops, &opts); err != nil {
+			return err
+		}
+	}
 
 	logger.Infof("Creating new cont for srv =>\t%s\n", srv.Name)
+	if err = j.Record("create "+ops.SrvContainerName, &Undo{Kind: UndoRemoveContainer, Args: []string{ops.SrvContainerName}}); err != nil {
+		return err
+	}
 	cont, err := createContainer(opts)
 	if err != nil {
 		return err
 	}
-	id = cont.ID
 
 	if wasRunning {
-		logger.Infof("Restarting srv with new ID =>\t%s\n", id)
-		err := startContainer(id, &opts)
-		if err != nil {
+		logger.Infof("Restarting srv with new ID =>\t%s\n", cont.ID)
+		if err = startContainer(cont.ID, &opts); err != nil {
 			return err
 		}
 	}
 
+	logger.Infof("Removing old container =>\t%s\n", service.ID)
+	if err = removeContainer(service.ID); err != nil {
+		return err
+	}
+
 	logger.Infof("Finished rebuilding service =>\t%s\n", srv.Name)
 
 	return nil
 }
 
+// DataContainerPaths lists the volumes of the data container of ops.
+func DataContainerPaths(ops *def.Operation) ([]string, error) {
+	dataCont, exists := ContainerDataContainerExists(ops)
+	if !exists {
+		return nil, fmt.Errorf("There is no data container %s.", ops.DataContainerName)
+	}
+	cont, err := util.DockerClient.InspectContainer(dataCont.ID)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	if cont.Config != nil {
+		for dir := range cont.Config.Volumes {
+			paths = append(paths, dir)
+		}
+	}
+	if len(paths) == 0 {
+		for dir := range cont.Volumes {
+			paths = append(paths, dir)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// DockerMigrateData moves the data of a service or chain out of its
+// data container into named volumes, one for each of srv.DataPaths
+// (the volumes of the data container, see DataContainerPaths), and
+// recreates the service container with those volumes mounted in place
+// of the data container. srv should have DataVolumes set. Each volume
+// is compared with the data container after it is copied, and the data
+// container is removed once everything else is done; if anything fails
+// before then the service container is put back the way it was.
+func DockerMigrateData(srv *def.Service, ops *def.Operation, timeout uint) (err error) {
+	dataCont, exists := ContainerDataContainerExists(ops)
+	if !exists {
+		return fmt.Errorf("%s has no data container to migrate.", srv.Name)
+	}
+	if len(srv.DataPaths) == 0 {
+		return fmt.Errorf("The marmots do not know which paths of %s to migrate.", ops.DataContainerName)
+	}
+	logger.Infof("Migrating data container =>\t%s\n", ops.DataContainerName)
+	paths := srv.DataPaths
+
+	j := NewJournal("Migrate", false)
+	defer func() {
+		if err != nil {
+			err = j.Rollback(err)
+			return
+		}
+		err = j.Commit()
+	}()
+
+	service, srvExists := ContainerExists(ops)
+	_, wasRunning := ContainerRunning(ops)
+	if wasRunning {
+		if err = j.Record("stop "+ops.SrvContainerName, &Undo{Kind: UndoStartContainer, Args: []string{ops.SrvContainerName}}); err != nil {
+			return err
+		}
+		if err = DockerStop(srv, ops, timeout); err != nil {
+			return err
+		}
+	}
+
+	// the data container is only read from; the volumes are left
+	// behind if the migration fails
+	for _, dir := range paths {
+		volume := util.DataVolumeName(ops.DataContainerName, dir)
+		logger.Infof("Copying into volume =>\t\t%s:%s\n", volume, dir)
+		if err = copyToVolume(ops.DataContainerName, dir, volume); err != nil {
+			return fmt.Errorf("The marmots could not copy %s into %s: %v", dir, volume, err)
+		}
+		logger.Infof("Checking volume =>\t\t%s:%s\n", volume, dir)
+		if err = checkVolume(ops.DataContainerName, dir, volume); err != nil {
+			return fmt.Errorf("The copy of %s in %s does not match the data container: %v", dir, volume, err)
+		}
+	}
+
+	if srvExists {
+		oldName := "migrate_" + service.ID
+		if len(service.ID) > 12 {
+			oldName = "migrate_" + service.ID[:12]
+		}
+		logger.Infof("Setting aside old container =>\t%s\n", service.ID)
+		if err = j.Record("set aside "+ops.SrvContainerName, &Undo{Kind: UndoRenameContainer, Args: []string{oldName, ops.SrvContainerName}}); err != nil {
+			return err
+		}
+		if err = renameContainer(service.ID, oldName); err != nil {
+			return err
+		}
+
+		var opts docker.CreateContainerOptions
+		if opts, err = configureServiceContainer(srv, ops); err != nil {
+			return err
+		}
+		if err = configureDataVolumes(srv, ops, &opts); err != nil {
+			return err
+		}
+
+		logger.Infof("Creating new cont for srv =>\t%s\n", srv.Name)
+		if err = j.Record("create "+ops.SrvContainerName, &Undo{Kind: UndoRemoveContainer, Args: []string{ops.SrvContainerName}}); err != nil {
+			return err
+		}
+		var newCont *docker.Container
+		if newCont, err = createContainer(opts); err != nil {
+			return err
+		}
+		if wasRunning {
+			logger.Infof("Restarting srv with new ID =>\t%s\n", newCont.ID)
+			if err = startContainer(newCont.ID, &opts); err != nil {
+				return err
+			}
+		}
+
+		logger.Infof("Removing old container =>\t%s\n", service.ID)
+		if err = removeContainer(service.ID); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Removing data container =>\t%s\n", dataCont.ID)
+	return util.DockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: dataCont.ID, RemoveVolumes: true})
+}
+
 func DockerPull(srv *def.Service, ops *def.Operation) error {
 	logger.Infof("Pulling an image (%s) for the service (%s)\n", srv.Image, srv.Name)
 
@@ -362,6 +653,15 @@ func DockerPull(srv *def.Service, ops *def.Operation) error {
 	return nil
 }
 
+// DockerPullImage pulls an image without touching any containers.
+func DockerPullImage(image string) error {
+	logger.Infof("Pulling an image =>\t\t%s\n", image)
+	if logger.Level > 0 {
+		return pullImage(image, logger.Writer)
+	}
+	return pullImage(image, bytes.NewBuffer([]byte{}))
+}
+
 func DockerLogs(srv *def.Service, ops *def.Operation, follow bool, tail string) error {
 	if service, exists := ContainerExists(ops); exists {
 		logger.Infof("Getting Logs for Service ID =>\t%s\n", service.ID)
@@ -431,6 +731,32 @@ func DockerRename(srv *def.Service, ops *def.Operation, oldName, newName string)
 	return nil
 }
 
+// AddRenameSteps adds a step to j for every container, of any number,
+// of type typ ("chain" or "service") named oldName and for each of
+// their data containers, renaming it to newName.
+func AddRenameSteps(j *Journal, typ, oldName, newName string) {
+	containers := append(util.ErisContainersByType(typ, true), util.DataContainers()...)
+	for _, cont := range containers {
+		if cont.ShortName != oldName {
+			continue
+		}
+
+		from := cont.FullName
+		to := util.ContainersName(cont.Type, newName, cont.Number)
+		j.Add(fmt.Sprintf("rename container %s to %s", from, to), func() error {
+			return renameByName(from, to)
+		}, &Undo{Kind: UndoRenameContainer, Args: []string{to, from}})
+	}
+}
+
+func renameByName(from, to string) error {
+	cont, exists := parseContainers("^/"+regexp.QuoteMeta(from)+"$", true)
+	if !exists {
+		return fmt.Errorf("no container %s", from)
+	}
+	return renameContainer(cont.ID, to)
+}
+
 func DockerRemove(srv *def.Service, ops *def.Operation, withData bool) error {
 	if service, exists := ContainerExists(ops); exists {
 		logger.Infof("Removing Service ID =>\t\t%s\n", service.ID)
@@ -449,6 +775,26 @@ func DockerRemove(srv *def.Service, ops *def.Operation, withData bool) error {
 		logger.Infoln("Service container does not exist. Cannot remove.")
 	}
 
+	// named volumes outlive the containers which mount them
+	if withData && srv.DataVolumes {
+		return DockerRemoveDataVolumes(ops.DataContainerName)
+	}
+	return nil
+}
+
+// DockerRemoveDataVolumes removes the named data volumes which belong
+// to the data container name.
+func DockerRemoveDataVolumes(dataContainerName string) error {
+	volumes, err := util.DataVolumes(dataContainerName)
+	if err != nil {
+		return err
+	}
+	for _, volume := range volumes {
+		logger.Infof("\t with Data Volume =>\t%s\n", volume)
+		if err := util.RemoveVolume(volume); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -836,6 +1182,55 @@ func configureDataContainer(srv *def.Service, ops *def.Operation, mainContOpts *
 	return opts, nil
 }
 
+// configureDataVolumes mounts the named data volumes of a service into
+// its container (see DataVolumeBinds). Docker makes the volumes the
+// first time they are used.
+func configureDataVolumes(srv *def.Service, ops *def.Operation, mainContOpts *docker.CreateContainerOptions) error {
+	volumes, err := DataVolumeBinds(srv, ops)
+	if err != nil {
+		return err
+	}
+	// a fresh slice; Binds starts out as the service's own volumes
+	mainContOpts.HostConfig.Binds = append(append([]string{}, mainContOpts.HostConfig.Binds...), volumes...)
+	return nil
+}
+
+// DataVolumeBinds lists the named volumes (volume:dir) of a service or
+// chain with data_volumes set: one at each of its data_paths, which eris
+// data migrate records from the data container, or else at each volume
+// its image declares, or at /home/eris/.eris if it declares none.
+func DataVolumeBinds(srv *def.Service, ops *def.Operation) ([]string, error) {
+	paths := append([]string{}, srv.DataPaths...)
+	if len(paths) == 0 {
+		image, err := util.DockerClient.InspectImage(srv.Image)
+		if err == docker.ErrNoSuchImage {
+			if err = DockerPullImage(srv.Image); err == nil {
+				image, err = util.DockerClient.InspectImage(srv.Image)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if image.Config != nil {
+			for dir := range image.Config.Volumes {
+				paths = append(paths, dir)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, "/home/eris/.eris")
+	}
+	sort.Strings(paths)
+
+	binds := []string{}
+	for _, dir := range paths {
+		volume := util.DataVolumeName(ops.DataContainerName, dir)
+		logger.Debugf("\twith Data Volume =>\t%s:%s\n", volume, dir)
+		binds = append(binds, volume+":"+dir)
+	}
+	return binds, nil
+}
+
 // ----------------------------------------------------------------------------
 // ---------------------    Exec Core -----------------------------------------
 // ----------------------------------------------------------------------------
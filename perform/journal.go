@@ -0,0 +1,319 @@
+package perform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/util"
+
+	dirs "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// the kinds of compensating action a journal can record
+const (
+	UndoRemoveContainer = "rm-container"     // name
+	UndoRenameContainer = "rename-container" // from, to
+	UndoStartContainer  = "start-container"  // name
+	UndoStopContainer   = "stop-container"   // name
+	UndoRemovePath      = "rm-path"          // path
+	UndoWriteFile       = "write-file"       // path, contents
+)
+
+// Undo is the compensating action for one side effect. Undos are
+// plain data so a journal can be written to disk and finished by
+// [eris recover] after a crash. An undo does not know whether its side
+// effect happened: removing a container or path which was already there
+// removes the user's. Record an undo only for what the operation is
+// about to make, after checking it is not there yet.
+type Undo struct {
+	Desc string   `json:"desc"`
+	Kind string   `json:"kind"`
+	Args []string `json:"args"`
+}
+
+// Journal records the side effects of an operation which touches
+// several containers and files, each with its undo. When the operation
+// fails the side effects are undone, newest first, so nothing is left
+// half done. The undos are saved under JournalPath() as they are
+// recorded; a journal left behind by a crash is finished by Recover.
+type Journal struct {
+	Name    string    `json:"name"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Undos   []*Undo   `json:"undos"`
+	DryRun  bool      `json:"-"`
+
+	steps []*journalStep
+	file  string
+	mu    sync.Mutex
+}
+
+type journalStep struct {
+	desc string
+	do   func() error
+	undo *Undo
+}
+
+// JournalPath is where the journals of operations in progress are kept.
+func JournalPath() string {
+	return path.Join(dirs.ErisRoot, "journal")
+}
+
+func NewJournal(name string, dryRun bool) *Journal {
+	return &Journal{Name: name, PID: os.Getpid(), Started: time.Now(), DryRun: dryRun}
+}
+
+// Add appends a step for Run. undo may be nil for steps with nothing
+// to undo.
+func (j *Journal) Add(desc string, do func() error, undo *Undo) {
+	if undo != nil {
+		undo.Desc = desc
+	}
+	j.steps = append(j.steps, &journalStep{desc: desc, do: do, undo: undo})
+}
+
+// Steps describes the steps in the order they run.
+func (j *Journal) Steps() []string {
+	descs := []string{}
+	for _, s := range j.steps {
+		descs = append(descs, s.desc)
+	}
+	return descs
+}
+
+// Run runs every step, or with DryRun only prints them. If a step fails
+// the completed steps are undone.
+func (j *Journal) Run() error {
+	if j.DryRun {
+		for _, desc := range j.Steps() {
+			logger.Printf("Would %s\n", desc)
+		}
+		return nil
+	}
+
+	for _, s := range j.steps {
+		logger.Infof("%s =>\t\t%s\n", j.Name, s.desc)
+		// recorded first: a crash in the middle of the step still
+		// leaves its undo behind
+		if s.undo != nil {
+			if err := j.Record(s.desc, s.undo); err != nil {
+				return j.Rollback(err)
+			}
+		}
+		if err := s.do(); err != nil {
+			return j.Rollback(fmt.Errorf("%s failed to %s: %v", j.Name, s.desc, err))
+		}
+	}
+	return j.Commit()
+}
+
+// Record notes a side effect and how to undo it. Record before the side
+// effect happens; the undo then also covers a crash half way through.
+// It is safe to call from several goroutines.
+func (j *Journal) Record(desc string, undo *Undo) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	undo.Desc = desc
+	j.Undos = append(j.Undos, undo)
+	return j.save()
+}
+
+// Commit forgets the recorded side effects once the operation is done.
+func (j *Journal) Commit() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Undos = nil
+	if j.file == "" {
+		return nil
+	}
+	if err := os.Remove(j.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	j.file = ""
+	return nil
+}
+
+// Rollback undoes the recorded side effects, newest first, and returns
+// cause with a note of what was undone. Undos which fail stay in the
+// journal on disk for [eris recover].
+func (j *Journal) Rollback(cause error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	failed := runUndos(j.Undos, false)
+	j.Undos = failed
+	if len(failed) != 0 {
+		if err := j.save(); err != nil {
+			logger.Errorf("The marmots could not save the journal: %v\n", err)
+		}
+		return fmt.Errorf("Tragic! %v\nThe marmots could not undo these steps:\n\n%s\nRetry with [eris recover].", cause, describeUndos(failed))
+	}
+
+	if j.file != "" {
+		os.Remove(j.file)
+		j.file = ""
+	}
+	return fmt.Errorf("%v\nThe marmots undid the completed steps.", cause)
+}
+
+// save writes the journal to disk; the file is only made once there is
+// something to undo.
+func (j *Journal) save() error {
+	if j.file == "" {
+		if err := os.MkdirAll(JournalPath(), 0755); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s_%d_%d.json", strings.ToLower(j.Name), j.PID, j.Started.UnixNano())
+		j.file = path.Join(JournalPath(), name)
+	}
+
+	raw, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.file, raw, 0600)
+}
+
+// Recover undoes the side effects of every journal left behind by an
+// operation which did not finish. Journals of eris processes which are
+// still running are skipped unless force is set. With dryRun the undos
+// are only printed.
+func Recover(force, dryRun bool) error {
+	files, err := filepath.Glob(path.Join(JournalPath(), "*.json"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		logger.Println("Nothing to recover.")
+		return nil
+	}
+
+	var failed []string
+	for _, file := range files {
+		j, err := loadJournal(file)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		if processAlive(j.PID) && !force {
+			logger.Printf("Still running =>\t\t%s (pid %d). Skipping it.\n", j.Name, j.PID)
+			continue
+		}
+
+		logger.Printf("Recovering =>\t\t\t%s from %s\n", j.Name, j.Started.Format(time.Stamp))
+		if dryRun {
+			for i := len(j.Undos) - 1; i >= 0; i-- {
+				logger.Printf("Would undo %s\n", j.Undos[i].Desc)
+			}
+			continue
+		}
+
+		j.Undos = runUndos(j.Undos, true)
+		if len(j.Undos) != 0 {
+			failed = append(failed, describeUndos(j.Undos))
+			if err := j.save(); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", file, err))
+			}
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", file, err))
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("The marmots could not recover everything:\n\n%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+func loadJournal(file string) (*Journal, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{}
+	if err := json.Unmarshal(raw, j); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the journal: %v", err)
+	}
+	j.file = file
+	return j, nil
+}
+
+// runUndos runs undos newest first and returns the ones which failed,
+// oldest first.
+func runUndos(undos []*Undo, loud bool) []*Undo {
+	failed := []*Undo{}
+	for i := len(undos) - 1; i >= 0; i-- {
+		u := undos[i]
+		if loud {
+			logger.Printf("Undoing =>\t\t\t%s\n", u.Desc)
+		} else {
+			logger.Infof("Undoing =>\t\t\t%s\n", u.Desc)
+		}
+		if err := runUndo(u); err != nil {
+			logger.Debugf("Undo failed =>\t\t%s: %v\n", u.Desc, err)
+			failed = append([]*Undo{u}, failed...)
+		}
+	}
+	return failed
+}
+
+func runUndo(u *Undo) error {
+	arg := func(i int) string {
+		if i < len(u.Args) {
+			return u.Args[i]
+		}
+		return ""
+	}
+
+	switch u.Kind {
+	case UndoRemoveContainer:
+		cont, exists := parseContainers("^/"+regexp.QuoteMeta(arg(0))+"$", true)
+		if !exists {
+			return nil
+		}
+		return util.DockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: cont.ID, Force: true})
+	case UndoRenameContainer:
+		if _, exists := parseContainers("^/"+regexp.QuoteMeta(arg(0))+"$", true); !exists {
+			return nil
+		}
+		return renameByName(arg(0), arg(1))
+	case UndoStartContainer:
+		cont, exists := parseContainers("^/"+regexp.QuoteMeta(arg(0))+"$", true)
+		if !exists {
+			return fmt.Errorf("no container %s", arg(0))
+		}
+		return util.DockerClient.StartContainer(cont.ID, nil)
+	case UndoStopContainer:
+		cont, exists := parseContainers("^/"+regexp.QuoteMeta(arg(0))+"$", false)
+		if !exists {
+			return nil
+		}
+		return stopContainer(cont.ID, 10)
+	case UndoRemovePath:
+		return os.RemoveAll(arg(0))
+	case UndoWriteFile:
+		return ioutil.WriteFile(arg(0), []byte(arg(1)), 0644)
+	}
+	return fmt.Errorf("unknown undo %s", u.Kind)
+}
+
+func describeUndos(undos []*Undo) string {
+	descs := []string{}
+	for _, u := range undos {
+		descs = append(descs, u.Desc)
+	}
+	return strings.Join(descs, "\n")
+}
@@ -0,0 +1,134 @@
+package perform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dirs "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+func TestJournalRollback(t *testing.T) {
+	dir := tempErisRoot(t)
+	defer os.RemoveAll(dir)
+
+	var log []string
+	j := NewJournal("Test", false)
+	for _, s := range []struct {
+		name string
+		fail bool
+	}{{"a", false}, {"b", false}, {"c", true}, {"d", false}} {
+		name, fail := s.name, s.fail
+		file := path.Join(dir, name)
+		j.Add(name, func() error {
+			if fail {
+				return fmt.Errorf("boom")
+			}
+			log = append(log, "do "+name)
+			return ioutil.WriteFile(file, []byte(name), 0644)
+		}, &Undo{Kind: UndoRemovePath, Args: []string{file}})
+	}
+
+	err := j.Run()
+	if err == nil || !strings.Contains(err.Error(), "failed to c") {
+		t.Fatalf("Expected step c to fail, got %v", err)
+	}
+	if got := strings.Join(log, ","); got != "do a,do b" {
+		t.Fatalf("Wrong steps run. Got %s, expected do a,do b", got)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := os.Stat(path.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("Step %s was not undone: %v", name, err)
+		}
+	}
+	if left := journalFiles(t); len(left) != 0 {
+		t.Fatalf("Journal left behind after a rollback: %v", left)
+	}
+
+	log = nil
+	j.DryRun = true
+	if err := j.Run(); err != nil || len(log) != 0 {
+		t.Fatalf("Dry run ran steps: %v %v", log, err)
+	}
+	if got := strings.Join(j.Steps(), ","); got != "a,b,c,d" {
+		t.Fatalf("Wrong steps. Got %s, expected a,b,c,d", got)
+	}
+}
+
+func TestJournalRecover(t *testing.T) {
+	dir := tempErisRoot(t)
+	defer os.RemoveAll(dir)
+
+	made := path.Join(dir, "made")
+	removed := path.Join(dir, "removed")
+
+	// a crash after both side effects, before Commit
+	j := NewJournal("Test", false)
+	if err := j.Record("make "+made, &Undo{Kind: UndoRemovePath, Args: []string{made}}); err != nil {
+		t.Fatalf("Could not record: %v", err)
+	}
+	ioutil.WriteFile(made, []byte("new"), 0644)
+	if err := j.Record("remove "+removed, &Undo{Kind: UndoWriteFile, Args: []string{removed, "old"}}); err != nil {
+		t.Fatalf("Could not record: %v", err)
+	}
+	if left := journalFiles(t); len(left) != 1 {
+		t.Fatalf("Expected one journal on disk, got %v", left)
+	}
+
+	if err := Recover(false, true); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if _, err := os.Stat(made); err != nil {
+		t.Fatalf("Dry run undid a step: %v", err)
+	}
+
+	if err := Recover(false, false); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if _, err := os.Stat(made); !os.IsNotExist(err) {
+		t.Fatalf("Recover did not remove %s: %v", made, err)
+	}
+	if raw, err := ioutil.ReadFile(removed); err != nil || string(raw) != "old" {
+		t.Fatalf("Recover did not restore %s: %s %v", removed, raw, err)
+	}
+	if left := journalFiles(t); len(left) != 0 {
+		t.Fatalf("Journal left behind after recovering: %v", left)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getppid()) {
+		t.Fatalf("The parent process %d was not seen as alive", os.Getppid())
+	}
+
+	done := exec.Command(os.Args[0], "-test.run=^$")
+	if err := done.Run(); err != nil {
+		t.Fatalf("Could not run a process: %v", err)
+	}
+	if processAlive(done.Process.Pid) {
+		t.Fatalf("The finished process %d was seen as alive", done.Process.Pid)
+	}
+}
+
+// tempErisRoot points the journals at a scratch directory.
+func tempErisRoot(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "eris_journal_")
+	if err != nil {
+		t.Fatalf("Could not make a temp dir: %v", err)
+	}
+	dirs.ErisRoot = dir
+	return dir
+}
+
+func journalFiles(t *testing.T) []string {
+	files, err := filepath.Glob(path.Join(JournalPath(), "*.json"))
+	if err != nil {
+		t.Fatalf("Could not list journals: %v", err)
+	}
+	return files
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package perform
+
+import (
+	"os"
+	"syscall"
+)
+
+// signal 0 checks a process exists without touching it
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,30 @@
+package perform
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// windows has no signal 0; a process is alive while it can be opened
+// and has no exit code yet
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,8 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/eris-ltd/eris-cli/data"
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/ipfs"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/util"
@@ -29,17 +28,7 @@ func ImportService(do *definitions.Do) error {
 	s := strings.Split(do.Path, ":")
 	if s[0] == "ipfs" {
 
-		var err error
-		if logger.Level > 0 {
-			err = util.GetFromIPFS(s[1], fileName, logger.Writer)
-		} else {
-			err = util.GetFromIPFS(s[1], fileName, bytes.NewBuffer([]byte{}))
-		}
-
-		if err != nil {
-			return err
-		}
-		return nil
+		return ipfs.Node.Get(s[1], fileName)
 	}
 
 	if strings.Contains(s[0], "github") {
@@ -88,7 +77,7 @@ func EditService(do *definitions.Do) error {
 }
 
 func RenameService(do *definitions.Do) error {
-	logger.Infof("Renaming Service =>\t\t%s:%s:%d\n", do.Name, do.NewName, do.Operations.ContainerNumber)
+	logger.Infof("Renaming Service =>\t\t%s:%s\n", do.Name, do.NewName)
 
 	if do.Name == do.NewName {
 		return fmt.Errorf("Cannot rename to same name")
@@ -97,57 +86,54 @@ func RenameService(do *definitions.Do) error {
 	newNameBase := strings.Replace(do.NewName, filepath.Ext(do.NewName), "", 1)
 	transformOnly := newNameBase == do.Name
 
-	if parseKnown(do.Name) {
-		serviceDef, err := loaders.LoadServiceDefinition(do.Name, false, do.Operations.ContainerNumber)
-		if err != nil {
-			return err
-		}
-
-		do.Operations.ContainerNumber = serviceDef.Operations.ContainerNumber
-
-		if !transformOnly {
-			logger.Debugf("Asking Docker to Service =>\t%s:%s:%d\n", do.Name, do.NewName, do.Operations.ContainerNumber)
-			err = perform.DockerRename(serviceDef.Service, serviceDef.Operations, do.Name, do.NewName)
-			if err != nil {
-				return err
-			}
-		} else {
-			logger.Infoln("Changing the service definition file type only. Not renaming container(s).")
-		}
+	if !parseKnown(do.Name) {
+		return fmt.Errorf("I cannot find that service. Please check the service name you sent me.")
+	}
+	if !transformOnly && parseKnown(newNameBase) {
+		return fmt.Errorf("The marmots already know a service called %s.", newNameBase)
+	}
 
-		oldFile := FindServiceDefinitionFile(do.Name)
+	serviceDef, err := loaders.LoadServiceDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
 
-		if filepath.Base(oldFile) == do.NewName {
-			logger.Infoln("Those are the same file. Not renaming")
-			return nil
-		}
+	oldFile := FindServiceDefinitionFile(do.Name)
+	if filepath.Base(oldFile) == do.NewName {
+		logger.Infoln("Those are the same file. Not renaming")
+		return nil
+	}
+	oldRaw, err := ioutil.ReadFile(oldFile)
+	if err != nil {
+		return err
+	}
 
-		var newFile string
-		if filepath.Ext(do.NewName) == "" {
-			newFile = strings.Replace(oldFile, do.Name, do.NewName, 1)
-		} else {
-			newFile = filepath.Join(ServicesPath, do.NewName)
-		}
+	var newFile string
+	if filepath.Ext(do.NewName) == "" {
+		newFile = strings.Replace(oldFile, do.Name, do.NewName, 1)
+	} else {
+		newFile = filepath.Join(ServicesPath, do.NewName)
+	}
 
-		serviceDef.Service.Name = strings.Replace(do.NewName, filepath.Ext(do.NewName), "", 1)
-		serviceDef.Name = serviceDef.Service.Name
-		err = WriteServiceDefinitionFile(serviceDef, newFile)
-		if err != nil {
-			return err
-		}
+	serviceDef.Service.Name = newNameBase
+	serviceDef.Name = serviceDef.Service.Name
 
-		if !transformOnly {
-			logger.Debugf("Calling Data Rename =>\t\t%s:%s:%d\n", do.Name, do.NewName, do.Operations.ContainerNumber)
-			err = data.RenameData(do)
-			if err != nil {
-				return err
-			}
-		}
-
-		os.Remove(oldFile)
+	j := perform.NewJournal("Rename", do.DryRun)
+	if !transformOnly {
+		perform.AddRenameSteps(j, "service", do.Name, newNameBase)
 	} else {
-		return fmt.Errorf("I cannot find that service. Please check the service name you sent me.")
+		logger.Infoln("Changing the service definition file type only. Not renaming container(s).")
 	}
+	j.Add("write "+newFile, func() error {
+		return WriteServiceDefinitionFile(serviceDef, newFile)
+	}, &perform.Undo{Kind: perform.UndoRemovePath, Args: []string{newFile}})
+	j.Add("remove "+oldFile, func() error {
+		return os.Remove(oldFile)
+	}, &perform.Undo{Kind: perform.UndoWriteFile, Args: []string{oldFile, string(oldRaw)}})
+	if err := j.Run(); err != nil {
+		return err
+	}
+
 	do.Result = "success"
 	return nil
 }
@@ -239,6 +225,49 @@ func UpdateService(do *definitions.Do) error {
 	return nil
 }
 
+// MigrateService moves the data of the service do.Name out of its data
+// container into named volumes, and sets data_volumes and data_paths
+// in its definition so it stays there.
+func MigrateService(do *definitions.Do) error {
+	service, err := loaders.LoadServiceDefinition(do.Name, false, do.Operations.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	if !service.Service.AutoData {
+		return fmt.Errorf("%s does not keep its data in a data container.", do.Name)
+	}
+
+	fileName := path.Join(ServicesPath, do.Name+".toml")
+	old, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("The marmots can only migrate services with TOML definitions (%s): %v", fileName, err)
+	}
+	// the volumes are mounted where the data container had its own
+	paths, err := perform.DataContainerPaths(service.Operations)
+	if err != nil {
+		return err
+	}
+	if err := util.SetTOMLKey(fileName, "service", "data_volumes", "true"); err != nil {
+		return err
+	}
+	if err := util.SetTOMLKey(fileName, "service", "data_paths", util.TOMLStrings(paths)); err != nil {
+		ioutil.WriteFile(fileName, old, 0644)
+		return err
+	}
+
+	service.Service.DataVolumes = true
+	service.Service.DataPaths = paths
+	if err := perform.DockerMigrateData(service.Service, service.Operations, do.Timeout); err != nil {
+		if err2 := ioutil.WriteFile(fileName, old, 0644); err2 != nil {
+			logger.Errorf("The marmots could not put back %s: %v\n", fileName, err2)
+		}
+		return err
+	}
+	logger.Printf("Migrated =>\t\t\t%s\n", do.Name)
+	do.Result = "success"
+	return nil
+}
+
 func RmService(do *definitions.Do) error {
 	for _, servName := range do.Args {
 		service, err := loaders.LoadServiceDefinition(servName, false, do.Operations.ContainerNumber)
@@ -300,17 +329,5 @@ func InspectServiceByService(srv *definitions.Service, ops *definitions.Operatio
 func exportFile(servName string) (string, error) {
 	fileName := FindServiceDefinitionFile(servName)
 
-	var hash string
-	var err error
-	if logger.Level > 0 {
-		hash, err = util.SendToIPFS(fileName, logger.Writer)
-	} else {
-		hash, err = util.SendToIPFS(fileName, bytes.NewBuffer([]byte{}))
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	return hash, nil
+	return ipfs.Node.Add(fileName)
 }
@@ -9,6 +9,11 @@ import (
 	"sync"
 )
 
+// WaitForChain waits for a chain started along with services to come
+// up before the services are started. The chains package, which
+// imports this one, sets it; until then chains are not waited for.
+var WaitForChain = func(name string, cNum int) error { return nil }
+
 func StartService(do *definitions.Do) (err error) {
 	var services []*definitions.ServiceDefinition
 
@@ -32,23 +37,70 @@ func StartService(do *definitions.Do) (err error) {
 		util.OverWriteOperations(s.Operations, do.Operations)
 	}
 
-	services, err = BuildChainGroup(do.ChainName, services)
+	chains, err := chainGroup(do.ChainName, services)
 	if err != nil {
 		return err
 	}
 
+	// if one service fails to start, the ones started along with it
+	// are put back the way they were
+	j := perform.NewJournal("Start", false)
+	for _, s := range append(chains, services...) {
+		if err := RecordStart(j, s); err != nil {
+			return err
+		}
+	}
+
+	// the chains go first and are producing blocks before the
+	// services which use them start
+	if err := startGroupAndWait(chains); err != nil {
+		return j.Rollback(err)
+	}
+	for _, chain := range chains {
+		if err := WaitForChain(chain.Name, chain.Operations.ContainerNumber); err != nil {
+			return j.Rollback(err)
+		}
+	}
+	if err := startGroupAndWait(services); err != nil {
+		return j.Rollback(err)
+	}
+
+	return j.Commit()
+}
+
+func startGroupAndWait(group []*definitions.ServiceDefinition) error {
 	// TODO: move this wg, ch logic into func StartGroup([]*definitions.ServiceDefinition) error {}
-	wg, ch := new(sync.WaitGroup), make(chan error, 1)
-	StartGroup(ch, wg, services)
-	go func() {
-		wg.Wait()
-		ch <- nil
-	}()
-	if err := <-ch; err != nil {
-		return err
+	wg, ch := new(sync.WaitGroup), make(chan error, len(group))
+	StartGroup(ch, wg, group)
+	wg.Wait()
+	close(ch)
+	return <-ch
+}
+
+// RecordStart notes in j how to undo starting s: containers which
+// DockerRun is about to create are removed again, an existing container
+// is only stopped.
+func RecordStart(j *perform.Journal, s *definitions.ServiceDefinition) error {
+	ops := s.Operations
+	if _, running := perform.ContainerRunning(ops); running {
+		return nil
 	}
 
-	return nil
+	if s.Service.AutoData && !s.Service.DataVolumes {
+		if _, exists := perform.ContainerDataContainerExists(ops); !exists {
+			undo := &perform.Undo{Kind: perform.UndoRemoveContainer, Args: []string{ops.DataContainerName}}
+			if err := j.Record("create "+ops.DataContainerName, undo); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, exists := perform.ContainerExists(ops); !exists {
+		undo := &perform.Undo{Kind: perform.UndoRemoveContainer, Args: []string{ops.SrvContainerName}}
+		return j.Record("create "+ops.SrvContainerName, undo)
+	}
+	undo := &perform.Undo{Kind: perform.UndoStopContainer, Args: []string{ops.SrvContainerName}}
+	return j.Record("start "+ops.SrvContainerName, undo)
 }
 
 func KillService(do *definitions.Do) error {
@@ -136,6 +188,15 @@ func StartGroup(ch chan error, wg *sync.WaitGroup, group []*definitions.ServiceD
 // the command will add to the group a single chain passed into the group as well as
 // individualized chains that each service may individually rely upon.
 func BuildChainGroup(chainName string, services []*definitions.ServiceDefinition) ([]*definitions.ServiceDefinition, error) {
+	chains, err := chainGroup(chainName, services)
+	if err != nil {
+		return nil, err
+	}
+	return append(services, chains...), nil
+}
+
+// chainGroup is the chains the services rely upon.
+func chainGroup(chainName string, services []*definitions.ServiceDefinition) ([]*definitions.ServiceDefinition, error) {
 	var chains []*definitions.ServiceDefinition
 
 	for _, srv := range services {
@@ -161,7 +222,7 @@ func BuildChainGroup(chainName string, services []*definitions.ServiceDefinition
 		}
 	}
 
-	return append(services, chains...), nil
+	return chains, nil
 }
 
 func ChainConnectedToAService(chainName string, srv *definitions.ServiceDefinition) (*definitions.ServiceDefinition, error) {
@@ -170,8 +231,9 @@ func ChainConnectedToAService(chainName string, srv *definitions.ServiceDefiniti
 		return nil, err
 	}
 
-	loaders.ConnectToAService(srv, chainName) // first make the service container linked to the chain
-	loaders.ConnectToAService(s, srv.Name)    // now make the chain container linked to the service container
+	// only the service container is linked to the chain: the chain is
+	// started first and cannot link to a service which is not running yet
+	loaders.ConnectToAService(srv, chainName)
 	// XXX: we may have name collision here if we're not careful.
 
 	util.OverWriteOperations(s.Operations, srv.Operations)
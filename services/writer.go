@@ -59,6 +59,14 @@ func WriteServiceDefinitionFile(serviceDef *def.ServiceDefinition, fileName stri
 		if serviceDef.Chain != "" {
 			writer.Write([]byte("chain = \"" + serviceDef.Chain + "\"\n\n"))
 		}
+		if serviceDef.ChainName != "" {
+			writer.Write([]byte("chain_name = \"" + serviceDef.ChainName + "\"\n"))
+			writer.Write([]byte("chain_type = \"" + serviceDef.ChainType + "\"\n"))
+			if serviceDef.ErisDBVersion != "" {
+				writer.Write([]byte("erisdb_version = \"" + serviceDef.ErisDBVersion + "\"\n"))
+			}
+			writer.Write([]byte("\n"))
+		}
 		writer.Write([]byte("[service]\n"))
 		enc.Encode(serviceDef.Service)
 		writer.Write([]byte("\n[maintainer]\n"))
@@ -35,6 +35,13 @@ cd ../services && go test
 passed Services
 cd ../chains && go test
 passed Chains
+eris chains new smoke_ci --run --test
+eris chains test smoke_ci --junit "${CIRCLE_TEST_REPORTS:-/tmp}/chains/smoke_ci.xml"
+eris chains stop smoke_ci
+if [ -z "$CIRCLE_BUILD_NUM" ]; then
+  eris chains rm smoke_ci --file --data # circle cannot remove containers
+fi
+passed "Chain Smoke Test"
 cd ../actions && go test
 passed Actions
 cd ../contracts && go test
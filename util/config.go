@@ -28,6 +28,18 @@ type ErisConfig struct {
 	DockerHost     string `json:"DockerHost,omitempty" yaml:"DockerHost,omitempty" toml:"DockerHost,omitempty"`
 	DockerCertPath string `json:"DockerCertPath,omitempty" yaml:"DockerCertPath,omitempty" toml:"DockerCertPath,omitempty"`
 
+	// remove expired throwaway chains, without asking, before every command
+	GCOnStartup bool `json:"GCOnStartup,omitempty" yaml:"GCOnStartup,omitempty" toml:"GCOnStartup,omitempty"`
+	// idle throwaway chains eris contracts test keeps around, and
+	// whether leased ones are reset to a snapshot instead of removed
+	ChainPoolSize  int  `json:"ChainPoolSize,omitempty" yaml:"ChainPoolSize,omitempty" toml:"ChainPoolSize,omitempty"`
+	ChainPoolReset bool `json:"ChainPoolReset,omitempty" yaml:"ChainPoolReset,omitempty" toml:"ChainPoolReset,omitempty"`
+	// times a failed download is tried again, waiting DownloadBackoff
+	// (a duration such as 2s) before the first retry and twice as long
+	// before each one after
+	DownloadRetries int    `json:"DownloadRetries,omitempty" yaml:"DownloadRetries,omitempty" toml:"DownloadRetries,omitempty"`
+	DownloadBackoff string `json:"DownloadBackoff,omitempty" yaml:"DownloadBackoff,omitempty" toml:"DownloadBackoff,omitempty"`
+
 	Verbose bool
 }
 
@@ -81,6 +93,8 @@ func SetDefaults() (*viper.Viper, error) {
 	var globalConfig = viper.New()
 	globalConfig.SetDefault("IpfsHost", "http://0.0.0.0")
 	globalConfig.SetDefault("CompilersHost", "https://compilers.eris.industries")
+	globalConfig.SetDefault("DownloadRetries", 3)
+	globalConfig.SetDefault("DownloadBackoff", "1s")
 	return globalConfig, nil
 }
 
@@ -94,6 +94,13 @@ func DataContainersName(name string, number int) string {
 	return ContainersName("data", name, number)
 }
 
+// VolumesHolderName names the container eris makes for a moment to
+// reach the named data volumes of a service or chain which has no
+// container of its own.
+func VolumesHolderName(name string, number int) string {
+	return ContainersName("volumes", name, number)
+}
+
 func ServiceToDataContainer(serviceContainerName string) string {
 	return strings.Replace(serviceContainerName, "service", "data", 1)
 }
@@ -298,6 +305,61 @@ func IsDataContainer(name string, number int) bool {
 	return true
 }
 
+// DataVolumeName names the named volume which holds dir for a service
+// or chain with data_volumes set, after its data container. The usual
+// /home/eris/.eris is named just like the data container.
+func DataVolumeName(dataContainerName, dir string) string {
+	if dir == "/home/eris/.eris" {
+		return dataContainerName
+	}
+	suffix := regexp.MustCompile(`[^a-zA-Z0-9_.-]+`).ReplaceAllString(strings.Trim(dir, "/"), "-")
+	return dataContainerName + "-" + suffix
+}
+
+// DataVolumeMounts lists where the named data volumes of a service or
+// chain container are mounted.
+func DataVolumeMounts(cont *docker.Container) []string {
+	mounts := []string{}
+	if cont.HostConfig == nil {
+		return mounts
+	}
+	name := ContainerDisassemble(cont.Name)
+	prefix := DataContainersName(name.ShortName, name.Number)
+	for _, bind := range cont.HostConfig.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) < 2 {
+			continue
+		}
+		if parts[0] == prefix || strings.HasPrefix(parts[0], prefix+"-") {
+			mounts = append(mounts, parts[1])
+		}
+	}
+	return mounts
+}
+
+// FindDataHolder returns the existing container holding the data of
+// name: its data container or, for services and chains with
+// data_volumes set, the service or chain container its named volumes
+// are mounted in. It returns "" if there is neither; named volumes
+// with no container mounting them are only found by their definition
+// (see data.HoldData) or by DataVolumes.
+func FindDataHolder(name string, number int) string {
+	if FindDataContainer(name, number) != nil {
+		return DataContainersName(name, number)
+	}
+	for _, holder := range []string{ServiceContainersName(name, number), ChainContainersName(name, number)} {
+		cont, err := DockerClient.InspectContainer(holder)
+		if err != nil {
+			continue
+		}
+		if len(DataVolumeMounts(cont)) != 0 {
+			logger.Debugf("Found Data volumes in =>	%s\n", holder)
+			return holder
+		}
+	}
+	return ""
+}
+
 func erisRegExp(typ string) *regexp.Regexp {
 	return regexp.MustCompile(fmt.Sprintf(`\/eris_%s_(.+?)_(\d+)`, typ))
 }
@@ -2,6 +2,8 @@ package util
 
 import (
 	"testing"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 )
 
 var testsGood = []struct {
@@ -59,3 +61,26 @@ func TestContainerNameBad(t *testing.T) {
 		}
 	}
 }
+
+func TestDataVolumes(t *testing.T) {
+	if name := DataVolumeName("eris_data_ipfs_1", "/home/eris/.eris"); name != "eris_data_ipfs_1" {
+		t.Fatalf("Wrong volume name. Got %s, expected eris_data_ipfs_1", name)
+	}
+	if name := DataVolumeName("eris_data_ipfs_1", "/var/lib/ipfs data/"); name != "eris_data_ipfs_1-var-lib-ipfs-data" {
+		t.Fatalf("Wrong volume name. Got %s, expected eris_data_ipfs_1-var-lib-ipfs-data", name)
+	}
+
+	cont := &docker.Container{
+		Name: "/eris_service_ipfs_1",
+		HostConfig: &docker.HostConfig{Binds: []string{
+			"/home/marmot/scratch:/scratch",
+			"eris_data_ipfs_1:/home/eris/.eris",
+			"eris_data_ipfs_1-var-lib-ipfs:/var/lib/ipfs:ro",
+			"eris_data_ipfs_10:/elsewhere",
+		}},
+	}
+	mounts := DataVolumeMounts(cont)
+	if len(mounts) != 2 || mounts[0] != "/home/eris/.eris" || mounts[1] != "/var/lib/ipfs" {
+		t.Fatalf("Wrong data volume mounts. Got %v, expected [/home/eris/.eris /var/lib/ipfs]", mounts)
+	}
+}
@@ -17,6 +17,9 @@ import (
 // Docker Client initialization
 var DockerClient *docker.Client
 
+// where DockerClient connects, for the requests it cannot make itself
+var dockerEndpoint string
+
 func DockerConnect(verbose bool, machName string) { // TODO: return an error...?
 	var err error
 
@@ -25,6 +28,7 @@ func DockerConnect(verbose bool, machName string) { // TODO: return an error...?
 
 		logger.Debugln("Connecting to the Docker Client via:", endpoint)
 		DockerClient, err = docker.NewClient(endpoint)
+		dockerEndpoint = endpoint
 		if err != nil {
 			logger.Printf("%v\n", mustInstallError())
 			os.Exit(1)
@@ -56,6 +60,7 @@ func DockerConnect(verbose bool, machName string) { // TODO: return an error...?
 		logger.Debugln("Docker Certificate Path:", dockerCertPath)
 
 		DockerClient, err = docker.NewTLSClient(dockerHost, path.Join(dockerCertPath, "cert.pem"), path.Join(dockerCertPath, "key.pem"), path.Join(dockerCertPath, "ca.pem"))
+		dockerEndpoint = strings.TrimSpace(dockerHost)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -261,3 +266,47 @@ func setIPFSHostViaDockerHost(dockerHost string) {
 	logger.Debugf("Set ERIS_IPFS_HOST to =>\t%s\n", dockerIP)
 	os.Setenv("ERIS_IPFS_HOST", dockerIP)
 }
+
+// DockerHostIP returns the address at which ports published by
+// containers can be reached from the host eris is running on.
+func DockerHostIP() string {
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if dockerHost == "" || strings.HasPrefix(dockerHost, "unix://") {
+		return "127.0.0.1"
+	}
+
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		return "127.0.0.1"
+	}
+	dIP, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return u.Host
+	}
+	return dIP
+}
+
+// ContainerAddress finds the host:port where the given port of a
+// container can be reached. Published ports are preferred over the
+// container's own address on the docker bridge.
+func ContainerAddress(cont *docker.Container, port string) (string, error) {
+	if cont.NetworkSettings == nil {
+		return "", fmt.Errorf("The container (%s) has no network settings.", cont.Name)
+	}
+
+	if bindings, ok := cont.NetworkSettings.Ports[docker.Port(port)]; ok && len(bindings) != 0 {
+		host := bindings[0].HostIP
+		if host == "" || host == "0.0.0.0" {
+			host = DockerHostIP()
+		}
+		logger.Debugf("Found published port =>\t%s:%s\n", host, bindings[0].HostPort)
+		return net.JoinHostPort(host, bindings[0].HostPort), nil
+	}
+
+	if cont.NetworkSettings.IPAddress != "" {
+		logger.Debugf("Port not published =>\t\tusing container address %s\n", cont.NetworkSettings.IPAddress)
+		return net.JoinHostPort(cont.NetworkSettings.IPAddress, docker.Port(port).Port()), nil
+	}
+
+	return "", fmt.Errorf("The marmots could not find the port (%s) of the container (%s).\nTry restarting it with --publish.", port, cont.Name)
+}
@@ -0,0 +1,171 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the version of the Docker API which brought named volumes
+const volumesAPIVersion = "v1.21"
+
+// named data volumes: eris_data_<name>_<number>, with -<dir> after it
+// for any but /home/eris/.eris (see DataVolumeName)
+var dataVolumeName = regexp.MustCompile(`\Aeris_data_(.+?)_(\d+)(-.*)?\z`)
+
+// Volume is a named volume as the Docker API lists it.
+type Volume struct {
+	Name       string
+	Driver     string
+	Mountpoint string
+}
+
+// ListVolumes lists the named volumes on the Docker host.
+// go-dockerclient has no volumes API yet, so the request is made here
+// much as the client makes its own.
+func ListVolumes() ([]*Volume, error) {
+	body, status, err := dockerRequest("GET", "/volumes")
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("The marmots could not list the volumes: %s", strings.TrimSpace(string(body)))
+	}
+
+	list := struct {
+		Volumes []*Volume
+	}{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the volumes: %v", err)
+	}
+	return list.Volumes, nil
+}
+
+// RemoveVolume removes a named volume. A volume which is not there is
+// not an error.
+func RemoveVolume(name string) error {
+	body, status, err := dockerRequest("DELETE", "/volumes/"+url.QueryEscape(name))
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusNoContent, http.StatusOK, http.StatusNotFound:
+		return nil
+	}
+	return fmt.Errorf("The marmots could not remove the volume %s: %s", name, strings.TrimSpace(string(body)))
+}
+
+// DataVolumes lists the named data volumes which belong to the data
+// container name, sorted.
+func DataVolumes(dataContainerName string) ([]string, error) {
+	volumes, err := ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, v := range volumes {
+		if owner := DataVolumeOwner(v.Name); owner != nil && owner.FullName == dataContainerName {
+			names = append(names, v.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DataVolumeOwners lists, once each, the services and chains which
+// have named data volumes, named like their data containers would be.
+func DataVolumeOwners() ([]*ContainerName, error) {
+	volumes, err := ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	owners := []*ContainerName{}
+	for _, v := range volumes {
+		owner := DataVolumeOwner(v.Name)
+		if owner == nil || seen[owner.FullName] {
+			continue
+		}
+		seen[owner.FullName] = true
+		owners = append(owners, owner)
+	}
+	sort.Sort(byFullName(owners))
+	return owners, nil
+}
+
+// DataVolumeOwner tells whose data a named volume holds, or nil if it
+// is not an eris data volume.
+func DataVolumeOwner(volume string) *ContainerName {
+	match := dataVolumeName.FindStringSubmatch(volume)
+	if match == nil {
+		return nil
+	}
+	number, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil
+	}
+	return ContainerAssemble("data", match[1], number)
+}
+
+type byFullName []*ContainerName
+
+func (s byFullName) Len() int           { return len(s) }
+func (s byFullName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFullName) Less(i, j int) bool { return s[i].FullName < s[j].FullName }
+
+// dockerRequest makes an API request to the Docker host DockerClient
+// is connected to and returns the body and status of the response.
+func dockerRequest(method, path string) ([]byte, int, error) {
+	endpoint, err := url.Parse(dockerEndpoint)
+	if err != nil || dockerEndpoint == "" {
+		return nil, 0, fmt.Errorf("The marmots do not know where Docker is (%s).", dockerEndpoint)
+	}
+
+	req, err := http.NewRequest(method, "/"+volumesAPIVersion+path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var resp *http.Response
+	if endpoint.Scheme == "unix" {
+		conn, err := net.Dial("unix", endpoint.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer conn.Close()
+		req.Host = "docker"
+		if err := req.Write(conn); err != nil {
+			return nil, 0, err
+		}
+		if resp, err = http.ReadResponse(bufio.NewReader(conn), req); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		client := http.DefaultClient
+		scheme := "http"
+		if DockerClient != nil && DockerClient.HTTPClient != nil {
+			client = DockerClient.HTTPClient
+			if DockerClient.TLSConfig != nil {
+				scheme = "https"
+			}
+		}
+		if req.URL, err = url.Parse(scheme + "://" + endpoint.Host + req.URL.Path); err != nil {
+			return nil, 0, err
+		}
+		if resp, err = client.Do(req); err != nil {
+			return nil, 0, err
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
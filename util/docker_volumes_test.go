@@ -0,0 +1,73 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDataVolumeOwners(t *testing.T) {
+	volumes := map[string]bool{
+		"eris_data_ipfs_1":                 true,
+		"eris_data_ipfs_1-var-lib-ipfs":    true,
+		"eris_data_ipfs_10":                true,
+		"eris_data_my_chain_2-home-eris-x": true,
+		"3f2a9c0d":                         true,
+		"eris_service_ipfs_1":              true,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/"+volumesAPIVersion+"/volumes":
+			list := []*Volume{}
+			for name := range volumes {
+				list = append(list, &Volume{Name: name, Driver: "local"})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Volumes": list})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/"+volumesAPIVersion+"/volumes/"):
+			name := strings.TrimPrefix(r.URL.Path, "/"+volumesAPIVersion+"/volumes/")
+			if !volumes[name] {
+				http.Error(w, "no such volume", http.StatusNotFound)
+				return
+			}
+			delete(volumes, name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	defer func(endpoint string) { dockerEndpoint = endpoint }(dockerEndpoint)
+	dockerEndpoint = strings.Replace(server.URL, "http://", "tcp://", 1)
+
+	owners, err := DataVolumeOwners()
+	if err != nil {
+		t.Fatalf("Could not list the volume owners: %v", err)
+	}
+	names := []string{}
+	for _, owner := range owners {
+		names = append(names, owner.FullName)
+	}
+	if expected := []string{"eris_data_ipfs_1", "eris_data_ipfs_10", "eris_data_my_chain_2"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Wrong volume owners. Got %v, expected %v", names, expected)
+	}
+
+	held, err := DataVolumes("eris_data_ipfs_1")
+	if expected := []string{"eris_data_ipfs_1", "eris_data_ipfs_1-var-lib-ipfs"}; err != nil || !reflect.DeepEqual(held, expected) {
+		t.Fatalf("Wrong data volumes. Got %v (%v), expected %v", held, err, expected)
+	}
+
+	for _, name := range held {
+		if err := RemoveVolume(name); err != nil {
+			t.Fatalf("Could not remove %s: %v", name, err)
+		}
+	}
+	if err := RemoveVolume("eris_data_ipfs_1"); err != nil {
+		t.Fatalf("Removing a missing volume failed: %v", err)
+	}
+	if held, _ := DataVolumes("eris_data_ipfs_1"); len(held) != 0 {
+		t.Fatalf("Volumes left after removing them: %v", held)
+	}
+}
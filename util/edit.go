@@ -2,6 +2,9 @@ package util
 
 import (
 	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
 	"strings"
 
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
@@ -32,3 +35,59 @@ func Edit(conf *viper.Viper, configVals []string) error {
 
 	return nil
 }
+
+// TOMLStrings formats values as a TOML array of strings, for SetTOMLKey.
+func TOMLStrings(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// SetTOMLKey sets key in table, or at the top level when table is
+// empty, of a TOML file in place, leaving the rest of the file as the
+// user wrote it. value is written as is, so strings must be quoted. A
+// missing table is added at the end.
+func SetTOMLKey(fileName, table, key, value string) error {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	text := string(raw)
+	line := key + " = " + value
+
+	// a table runs from its [header] to the next one
+	start := 0
+	if table != "" {
+		header := regexp.MustCompile(`(?m)^[ \t]*\[` + regexp.QuoteMeta(table) + `\][ \t]*$`)
+		loc := header.FindStringIndex(text)
+		if loc == nil {
+			if text = strings.TrimRight(text, "\n"); text != "" {
+				text += "\n\n"
+			}
+			return ioutil.WriteFile(fileName, []byte(text+"["+table+"]\n"+line+"\n"), 0644)
+		}
+		start = loc[1]
+	}
+	end := len(text)
+	if loc := regexp.MustCompile(`(?m)^[ \t]*\[`).FindStringIndex(text[start:]); loc != nil {
+		end = start + loc[0]
+	}
+	head, body, tail := text[:start], text[start:end], text[end:]
+
+	re := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(key) + `[ \t]*=.*$`)
+	if re.MatchString(body) {
+		body = re.ReplaceAllLiteralString(body, line)
+	} else {
+		if body = strings.TrimRight(body, "\n"); body != "" || table != "" {
+			body += "\n"
+		}
+		body += line + "\n"
+		if tail != "" {
+			body += "\n"
+		}
+	}
+
+	return ioutil.WriteFile(fileName, []byte(head+body+tail), 0644)
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetTOMLKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_edit_")
+	if err != nil {
+		t.Fatalf("Could not make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "ipfs.toml")
+
+	for _, c := range []struct {
+		table, key, value string
+		before, after     string
+	}{
+		{"", "name", `"two"`,
+			"# ipfs\nname = \"one\"\n\n[service]\nimage = \"eris/ipfs\"\n",
+			"# ipfs\nname = \"two\"\n\n[service]\nimage = \"eris/ipfs\"\n"},
+		{"service", "data_volumes", "true",
+			"name = \"ipfs\"\n\n[service]\nimage = \"eris/ipfs\"\n\n[maintainer]\nname = \"marmot\"\n",
+			"name = \"ipfs\"\n\n[service]\nimage = \"eris/ipfs\"\ndata_volumes = true\n\n[maintainer]\nname = \"marmot\"\n"},
+		{"service", "data_volumes", "true",
+			"[service]\ndata_volumes = false\nimage = \"eris/ipfs\"\n",
+			"[service]\ndata_volumes = true\nimage = \"eris/ipfs\"\n"},
+		{"service", "data_volumes", "true",
+			"[service]\n",
+			"[service]\ndata_volumes = true\n"},
+		{"service", "data_volumes", "true",
+			"name = \"ipfs\"\n",
+			"name = \"ipfs\"\n\n[service]\ndata_volumes = true\n"},
+		{"service", "data_paths", TOMLStrings([]string{"/home/eris/.eris", "/var/lib/ipfs"}),
+			"[service]\ndata_volumes = true\n",
+			"[service]\ndata_volumes = true\ndata_paths = [\"/home/eris/.eris\", \"/var/lib/ipfs\"]\n"},
+	} {
+		ioutil.WriteFile(fileName, []byte(c.before), 0644)
+		if err := SetTOMLKey(fileName, c.table, c.key, c.value); err != nil {
+			t.Fatalf("Could not set %s: %v", c.key, err)
+		}
+		raw, _ := ioutil.ReadFile(fileName)
+		if string(raw) != c.after {
+			t.Fatalf("Wrong file after setting [%s] %s. Got:\n%s\nexpected:\n%s", c.table, c.key, raw, c.after)
+		}
+	}
+}
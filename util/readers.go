@@ -1,8 +1,6 @@
 package util
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,100 +20,78 @@ func GetFromGithub(org, repo, branch, path, fileName string, w io.Writer) error
 	return DownloadFromUrlToFile(url, fileName, w)
 }
 
-func GetFromIPFS(hash, fileName string, w io.Writer) error {
-	url := IPFSBaseGatewayUrl() + hash
-	w.Write([]byte("GETing file from IPFS. Hash =>\t" + hash + ":" + fileName + "\n"))
-	return DownloadFromUrlToFile(url, fileName, w)
-}
-
-func CatFromIPFS(fileHash string, w io.Writer) (string, error) {
-	url := IPFSBaseAPIUrl() + "cat?arg=" + fileHash
-	w.Write([]byte("CATing file from IPFS. Hash =>\t" + fileHash + "\n"))
-	body, err := PostAPICall(url, fileHash, w)
+// what the IPFS gateway answers with when it gives up on a hash
+const ipfsResolveTimeout = "Path Resolve error: context deadline exceeded"
 
-	if err != nil {
-		return "", err
-	}
-
-	return string(body), nil
+func DownloadFromUrlToFile(url, fileName string, w io.Writer) error {
+	return DownloadVerified(url, fileName, nil, w)
 }
 
-func ListFromIPFS(objectHash string, w io.Writer) (string, error) {
-	url := IPFSBaseAPIUrl() + "ls?arg=" + objectHash
-	w.Write([]byte("LISTing file from IPFS. objectHash =>\t" + objectHash + "\n"))
-	body, err := PostAPICall(url, objectHash, w)
-	r := bytes.NewReader(body)
-
-	type LsLink struct {
-		Name, Hash string
-		Size       uint64
-	}
-	type LsObject struct {
-		Hash  string
-		Links []LsLink
+// DownloadVerified downloads url to fileName. The download goes to
+// fileName.download first and only replaces fileName once it is whole
+// and check, if given, passes on it. Failed downloads are tried again
+// (see DownloadRetries in eris.toml), carrying on from where they
+// stopped when the server allows it; what a failed download leaves in
+// fileName.download is picked up by the next one as long as the server
+// still has the same file (its ETag or Last-Modified is kept next to the
+// partial download and sent back in If-Range). A download which fails
+// check is thrown away and started over.
+func DownloadVerified(url, fileName string, check func(tempName string) error, w io.Writer) error {
+	tokens := strings.Split(url, "/")
+	if fileName == "" {
+		fileName = tokens[len(tokens)-1]
 	}
+	w.Write([]byte("Downloading " + url + " to " + fileName + "\n"))
 
-	dec := json.NewDecoder(r)
-	out := struct{ Objects []LsObject }{}
-	err = dec.Decode(&out)
-	if err != nil {
-		return "", err
-	}
-	contents := out.Objects[0].Links
+	retries, backoff := downloadPolicy()
+	tempName := fileName + ".download"
+	var err error
+	for try := 0; try <= retries; try++ {
+		if try > 0 {
+			wait := backoff << uint(try-1)
+			w.Write([]byte(fmt.Sprintf("Download failed (%v). Trying again in %v.\n", err, wait)))
+			time.Sleep(wait)
+		}
 
-	res := make([]string, len(contents))
-	for i, c := range contents {
-		res[i] = c.Hash + " " + c.Name
+		var retry bool
+		if retry, err = download(url, tempName, w); err != nil {
+			if !retry {
+				break
+			}
+			continue
+		}
+		if check != nil {
+			if err = check(tempName); err != nil {
+				removeDownload(tempName)
+				continue
+			}
+		}
+		os.Remove(validatorName(tempName))
+		return os.Rename(tempName, fileName)
 	}
-	result := strings.Join(res, "\n")
-	return result, nil
+	return err
 }
 
-func ListPinnedFromIPFS(w io.Writer) (string, error) {
-	url := IPFSBaseAPIUrl() + "pin/ls"
-	w.Write([]byte("LISTing files pinned locally.\n"))
-	body, err := PostAPICall(url, "", w)
-	r := bytes.NewReader(body)
-
-	type RefKeyObject struct {
-		Type  string
-		Count int
+// download fetches url into tempName, carrying on from whatever is
+// already there when the server can tell it still has the same file.
+// It says whether a failure is worth trying again.
+func download(url, tempName string, w io.Writer) (bool, error) {
+	var offset int64
+	validator, _ := ioutil.ReadFile(validatorName(tempName))
+	if info, err := os.Stat(tempName); err == nil && len(validator) != 0 {
+		offset = info.Size()
 	}
 
-	type RefKeyList struct {
-		Keys map[string]RefKeyObject
-	}
-
-	var out RefKeyList
-	dec := json.NewDecoder(r)
-	err = dec.Decode(&out)
+	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return "", err
-	}
-	contents := out.Keys
-
-	res := make([]string, len(contents))
-	i := 0
-	for c := range contents {
-		res[i] = c
-		i += 1
+		return false, err
 	}
-	result := strings.Join(res, "\n")
-	return result, nil
-}
-
-func DownloadFromUrlToFile(url, fileName string, w io.Writer) error {
-	tokens := strings.Split(url, "/")
-	if fileName == "" {
-		fileName = tokens[len(tokens)-1]
-	}
-	w.Write([]byte("Downloading " + url + " to " + fileName + "\n"))
-
-	output, err := os.Create(fileName)
-	if err != nil {
-		return err
+	if offset > 0 {
+		w.Write([]byte(fmt.Sprintf("Resuming download at byte %d.\n", offset)))
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// a changed file is sent whole instead of spliced onto the old one
+		request.Header.Set("If-Range", string(validator))
 	}
-	defer output.Close()
 
 	// adding manual timeouts as IPFS hangs for a while
 	transport := http.Transport{
@@ -124,27 +100,102 @@ func DownloadFromUrlToFile(url, fileName string, w io.Writer) error {
 	client := http.Client{
 		Transport: &transport,
 	}
-	response, err := client.Get(url)
+	response, err := client.Do(request)
 	if err != nil {
-		return err
+		return true, err
 	}
 	defer response.Body.Close()
 
-	_, err = io.Copy(output, response.Body)
-	if err != nil {
-		return err
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case response.StatusCode == http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// what we have is not a start of what is there now
+		removeDownload(tempName)
+		return true, fmt.Errorf("%s changed while downloading", url)
+	case response.StatusCode >= http.StatusBadRequest:
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		if strings.TrimSpace(string(body)) == ipfsResolveTimeout {
+			return true, ipfsTimeoutError()
+		}
+		return response.StatusCode >= http.StatusInternalServerError, fmt.Errorf("could not download %s: %s", url, response.Status)
+	default:
+		// the server sent the whole thing
+		flags |= os.O_TRUNC
+		offset = 0
+		if v := responseValidator(response); v != "" {
+			if err := ioutil.WriteFile(validatorName(tempName), []byte(v), 0644); err != nil {
+				return false, err
+			}
+		} else {
+			os.Remove(validatorName(tempName))
+		}
 	}
 
-	body, err := ioutil.ReadFile(fileName)
+	output, err := os.OpenFile(tempName, flags, 0644)
+	if err != nil {
+		return false, err
+	}
+	n, err := io.Copy(output, response.Body)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return err
+		return true, err
+	}
+	if response.ContentLength >= 0 && n != response.ContentLength {
+		return true, fmt.Errorf("download of %s cut short after %d of %d bytes", url, offset+n, offset+response.ContentLength)
+	}
+
+	if offset == 0 && n == int64(len(ipfsResolveTimeout)) {
+		body, err := ioutil.ReadFile(tempName)
+		if err == nil && string(body) == ipfsResolveTimeout {
+			removeDownload(tempName)
+			return true, ipfsTimeoutError()
+		}
+	}
+	return false, nil
+}
+
+// validatorName is where the ETag or Last-Modified of a partial
+// download is kept.
+func validatorName(tempName string) string {
+	return tempName + ".validator"
+}
+
+// responseValidator is what a resumed download sends in If-Range to
+// make sure the file has not changed. Weak ETags cannot be used there.
+func responseValidator(response *http.Response) string {
+	if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
 	}
+	return response.Header.Get("Last-Modified")
+}
 
-	if string(body) == "Path Resolve error: context deadline exceeded" {
-		return fmt.Errorf("A timeout occured while trying to reach IPFS. Run `eris files cache [hash], wait 5-10 seconds, then run `eris files [cmd] [hash]`")
+func removeDownload(tempName string) {
+	os.Remove(tempName)
+	os.Remove(validatorName(tempName))
+}
+
+// downloadPolicy is how many times a download is retried and how long
+// to wait before the first retry.
+func downloadPolicy() (int, time.Duration) {
+	retries, backoff := 3, time.Second
+	if GlobalConfig == nil || GlobalConfig.Config == nil {
+		return retries, backoff
+	}
+	if GlobalConfig.Config.DownloadRetries >= 0 {
+		retries = GlobalConfig.Config.DownloadRetries
 	}
+	if d, err := time.ParseDuration(GlobalConfig.Config.DownloadBackoff); err == nil {
+		backoff = d
+	}
+	return retries, backoff
+}
 
-	return nil
+func ipfsTimeoutError() error {
+	return fmt.Errorf("A timeout occured while trying to reach IPFS. Run `eris files cache [hash], wait 5-10 seconds, then run `eris files [cmd] [hash]`")
 }
 
 // note this function fails silently.
@@ -197,31 +248,6 @@ func GetFileByNameAndType(typ, name string) string {
 	return ""
 }
 
-// --------------------------------------------------------------
-// Helper functions
-
-func IPFSBaseGatewayUrl() string {
-	return IPFSUrl() + ":8080/ipfs/"
-}
-
-func IPFSBaseAPIUrl() string {
-	return IPFSUrl() + ":5001/api/v0/"
-}
-
-func IPFSUrl() string {
-	var host string
-	if os.Getenv("ERIS_CLI_CONTAINER") == "true" {
-		host = "http://ipfs"
-	} else {
-		if os.Getenv("ERIS_IPFS_HOST") != "" {
-			host = os.Getenv("ERIS_IPFS_HOST")
-		} else {
-			host = GetConfigValue("IpfsHost")
-		}
-	}
-	return host
-}
-
 var timeout = time.Duration(10 * time.Second)
 
 func dialTimeout(network, addr string) (net.Conn, error) {
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDownloadVerified(t *testing.T) {
+	GlobalConfig = &ErisCli{Config: &ErisConfig{DownloadRetries: 2, DownloadBackoff: "1ms"}}
+	defer func() { GlobalConfig = nil }()
+
+	content := bytes.Repeat([]byte("eris"), 1024)
+	ranges, ifRanges := []string{}, []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", `"v1"`)
+		switch r.URL.Path {
+		case "/flaky":
+			// the first try is cut off half way
+			if len(ranges) == 1 {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write(content[:len(content)/2])
+				return
+			}
+			http.ServeContent(w, r, "flaky", time.Time{}, bytes.NewReader(content))
+		case "/whole":
+			http.ServeContent(w, r, "whole", time.Time{}, bytes.NewReader(content))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "eris_download_")
+	if err != nil {
+		t.Fatalf("Could not make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "file")
+
+	if err := DownloadVerified(server.URL+"/flaky", fileName, nil, ioutil.Discard); err != nil {
+		t.Fatalf("Could not download: %v", err)
+	}
+	if raw, _ := ioutil.ReadFile(fileName); !bytes.Equal(raw, content) {
+		t.Fatalf("expected %d bytes downloaded, got %d", len(content), len(raw))
+	}
+	if expected := fmt.Sprintf("bytes=%d-", len(content)/2); len(ranges) != 2 || ranges[1] != expected {
+		t.Fatalf("expected the second try to resume with %s, got %q", expected, ranges)
+	}
+	if ifRanges[1] != `"v1"` {
+		t.Fatalf("expected the second try to send If-Range \"v1\", got %q", ifRanges)
+	}
+	for _, left := range []string{fileName + ".download", fileName + ".download.validator"} {
+		if _, err := os.Stat(left); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be gone", left)
+		}
+	}
+
+	// a partial download of a file which has changed since is not
+	// spliced onto the new one
+	ranges, ifRanges = []string{}, []string{}
+	ioutil.WriteFile(fileName+".download", []byte("stale"), 0644)
+	ioutil.WriteFile(fileName+".download.validator", []byte(`"v0"`), 0644)
+	if err := DownloadVerified(server.URL+"/whole", fileName, nil, ioutil.Discard); err != nil {
+		t.Fatalf("Could not download: %v", err)
+	}
+	if raw, _ := ioutil.ReadFile(fileName); !bytes.Equal(raw, content) {
+		t.Fatalf("expected the stale part to be dropped, got %d bytes", len(raw))
+	}
+	if len(ifRanges) != 1 || ifRanges[0] != `"v0"` {
+		t.Fatalf("expected one try with If-Range \"v0\", got %q", ifRanges)
+	}
+
+	// without a validator there is nothing to resume from
+	ranges = []string{}
+	ioutil.WriteFile(fileName+".download", []byte("stale"), 0644)
+	if err := DownloadVerified(server.URL+"/whole", fileName, nil, ioutil.Discard); err != nil {
+		t.Fatalf("Could not download: %v", err)
+	}
+	if raw, _ := ioutil.ReadFile(fileName); !bytes.Equal(raw, content) || ranges[0] != "" {
+		t.Fatalf("expected a fresh download, got %d bytes and ranges %q", len(raw), ranges)
+	}
+
+	// a download which fails its check leaves the old file alone
+	checks := 0
+	err = DownloadVerified(server.URL+"/flaky", fileName, func(tempName string) error {
+		checks++
+		return fmt.Errorf("bad content")
+	}, ioutil.Discard)
+	if err == nil || checks != 3 {
+		t.Fatalf("expected the check to fail on every try, got %d checks and %v", checks, err)
+	}
+	if raw, _ := ioutil.ReadFile(fileName); !bytes.Equal(raw, content) {
+		t.Fatalf("expected the old file to be kept")
+	}
+	if _, err := os.Stat(fileName + ".download"); !os.IsNotExist(err) {
+		t.Fatalf("expected the bad download to be thrown away")
+	}
+
+	// not found is not worth trying again
+	ranges = []string{}
+	if err := DownloadVerified(server.URL+"/missing", fileName, nil, ioutil.Discard); err == nil || len(ranges) != 1 {
+		t.Fatalf("expected one try and an error, got %d tries and %v", len(ranges), err)
+	}
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TarDirectory writes the contents of dir to writer as a tar stream,
+// following symlinks like tar -h. Modes and times are kept; when owner
+// is given, every entry is owned by that user and group by name so tar
+// running as root in a container hands the files to that user.
+func TarDirectory(dir, owner string, writer io.Writer) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	tw := tar.NewWriter(writer)
+	err = WalkDirectory(dir, func(name, file string, info os.FileInfo) error {
+		return tarEntry(tw, file, name, owner, info)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// WalkDirectory calls fn for everything under dir, with its name
+// relative to dir, the way TarDirectory sees it: symlinks are followed
+// and a symlink back up the tree is not walked again. fn returning
+// filepath.SkipDir for a directory skips what is in it.
+func WalkDirectory(dir string, fn func(name, file string, info os.FileInfo) error) error {
+	return walkDir(dir, ".", fn, map[string]bool{})
+}
+
+// TarFiles writes only the named files of dir, given relative to dir,
+// to writer as a tar stream. The directories leading to each file are
+// written first so they get owner as well.
+func TarFiles(dir string, names []string, owner string, writer io.Writer) error {
+	tw := tar.NewWriter(writer)
+	written := map[string]bool{}
+	for _, name := range names {
+		parts := strings.Split(filepath.ToSlash(name), "/")
+		for i := 1; i <= len(parts); i++ {
+			entryName := strings.Join(parts[:i], "/")
+			if written[entryName] {
+				continue
+			}
+			written[entryName] = true
+
+			file := filepath.Join(dir, filepath.FromSlash(entryName))
+			info, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if err := tarEntry(tw, file, entryName, owner, info); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func walkDir(dir, name string, fn func(name, file string, info os.FileInfo) error, seen map[string]bool) error {
+	// a symlink back up the tree would otherwise never end
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if seen[real] {
+		return nil
+	}
+	seen[real] = true
+	defer delete(seen, real)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		file := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		entryName := filepath.ToSlash(filepath.Join(name, entry.Name()))
+		if err := fn(entryName, file, info); err != nil {
+			if err == filepath.SkipDir && info.IsDir() {
+				continue
+			}
+			return err
+		}
+		if info.IsDir() {
+			if err := walkDir(file, entryName, fn, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tarEntry(tw *tar.Writer, file, name, owner string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if owner != "" {
+		header.Uname = owner
+		header.Gname = owner
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_tar_")
+	if err != nil {
+		t.Fatalf("Could not make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "chains", "test"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "chains", "test", "genesis.json"), []byte("{}"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh"), 0755)
+	os.Symlink(filepath.Join(dir, "run.sh"), filepath.Join(dir, "link.sh"))
+	os.Symlink(dir, filepath.Join(dir, "chains", "loop"))
+
+	var buf bytes.Buffer
+	if err := TarDirectory(dir, "eris", &buf); err != nil {
+		t.Fatalf("Could not tar %s: %v", dir, err)
+	}
+
+	got := map[string]*tar.Header{}
+	contents := map[string]string{}
+	reader := tar.NewReader(&buf)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Could not read the tar stream: %v", err)
+		}
+		raw, _ := ioutil.ReadAll(reader)
+		got[header.Name] = header
+		contents[header.Name] = string(raw)
+	}
+
+	for name, mode := range map[string]int64{
+		"chains/":                  0700,
+		"chains/test/":             0700,
+		"chains/test/genesis.json": 0600,
+		"run.sh":                   0755,
+		"link.sh":                  0755,
+	} {
+		header, ok := got[name]
+		if !ok {
+			t.Fatalf("No %s in the tar stream. Got %v", name, got)
+		}
+		if header.Mode&0777 != mode {
+			t.Fatalf("Wrong mode on %s. Got %o, expected %o", name, header.Mode&0777, mode)
+		}
+		if header.Uname != "eris" || header.Gname != "eris" {
+			t.Fatalf("Wrong owner on %s. Got %s:%s, expected eris:eris", name, header.Uname, header.Gname)
+		}
+	}
+	if _, ok := got["chains/loop/run.sh"]; ok {
+		t.Fatalf("Followed the symlink loop back into %s", dir)
+	}
+	if contents["link.sh"] != "#!/bin/sh" {
+		t.Fatalf("Symlink was not followed. Got %q", contents["link.sh"])
+	}
+}